@@ -30,7 +30,13 @@ export class PGRestClient {
    * @param {string} query - The query string to execute.
    * @param {object} options - The options for the query.
    * @param {string} [options.connection] - The connection to use for the query. Defaults to the client's connection.
-   * @param {string} [options.format="json, jsonDataArray, csv, arrow, parquet"] - The format of the response. Defaults to "default". Options ["json", "jsonDataArray", "csv", "arrow", "parquet"].
+   * @param {any[]} [options.params] - The values bound to the query placeholders $1..$n.
+   * @param {string[]} [options.paramTypes] - Optional PostgreSQL type hint for each param, e.g. "int4" or "timestamptz".
+   * @param {string} [options.format="json, jsonDataArray, ndjson, geojson, csv, arrow, parquet"] - The format of the response. Defaults to "default". Options ["json", "jsonDataArray", "ndjson", "geojson", "csv", "arrow", "parquet"].
+   * @param {string} [options.geometryColumn] - The column to use as feature geometry for the geojson format.
+   * @param {string} [options.idColumn] - The column to use as feature id for the geojson format.
+   * @param {string} [options.compression] - The compression codec for the parquet format. Options ["snappy", "zstd", "gzip", "lz4", "none"].
+   * @param {number} [options.rowGroupSize] - The number of rows per row group for the parquet format.
    * @param {string} [options.encoding="gzip, br"] - The encoding to use for the response. Defaults to "gzip, br".
    * @param {function} [options.executionTimeFormatter] - A function to format the execution time. Defaults to the client's formatter.
    * @returns {Promise<object>} - A promise that resolves to the response from the server.
@@ -40,7 +46,13 @@ export class PGRestClient {
     query,
     {
       connection = this.#connection,
+      params = undefined,
+      paramTypes = undefined,
       format = "json",
+      geometryColumn = undefined,
+      idColumn = undefined,
+      compression = undefined,
+      rowGroupSize = undefined,
       encoding = "gzip, br",
       executionTimeFormatter = undefined,
     } = {}
@@ -51,7 +63,13 @@ export class PGRestClient {
 
     const body = JSON.stringify({
       query: query,
+      params: params,
+      paramTypes: paramTypes,
       format: format,
+      geometryColumn: geometryColumn,
+      idColumn: idColumn,
+      compression: compression,
+      rowGroupSize: rowGroupSize,
     });
     const contentType = this.#outputFormats[format].contentType;
     const startTime = performance.now();
@@ -107,6 +125,17 @@ export class PGRestClient {
     return csvData;
   }
 
+  /**
+   * @param {{ text: () => any; }} result
+   */
+  async #handleNDJSONResponse(result) {
+    const ndjsonData = await result.text();
+    return ndjsonData
+      .split("\n")
+      .filter((line) => line.length > 0)
+      .map((line) => JSON.parse(line));
+  }
+
   /**
    * @param {{ arrayBuffer: () => any; }} result
    */
@@ -162,6 +191,26 @@ export class PGRestClient {
           return await this.#handleParquetResponse(result);
         },
       },
+      geojson: {
+        contentType: "application/geo+json",
+        handler: async (
+          /** @type {{ json: () => any; }} */ result,
+          /** @type {number} */ duration,
+          /** @type {(arg0: any) => any} */ executionTimeFormatter
+        ) => {
+          return await this.#handleJSONResponse(
+            result,
+            duration,
+            executionTimeFormatter
+          );
+        },
+      },
+      ndjson: {
+        contentType: "application/x-ndjson",
+        handler: async (/** @type {{ text: () => any; }} */ result) => {
+          return await this.#handleNDJSONResponse(result);
+        },
+      },
       csv: {
         contentType: "text/csv",
         handler: async (/** @type {{ text: () => any; }} */ result) => {
@@ -0,0 +1,83 @@
+package flightsql
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sogelink-research/pgrest/settings"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tokenValidity is the duration a bearer token returned by the handshake is valid.
+const tokenValidity = 12 * time.Hour
+
+// authValidator authenticates Flight SQL clients with the clientId and clientSecret of a user as
+// basic auth username and password. The handshake returns a bearer token signed with the client secret,
+// so no session state is kept on the server.
+// The users of the current configuration are used, so they can be changed by reloading the configuration.
+type authValidator struct{}
+
+// Validate checks the credentials of the basic auth handshake and returns a bearer token for the user.
+func (v authValidator) Validate(username, password string) (string, error) {
+	user, ok := settings.GetConfig().UsersLookup[username]
+	if !ok || subtle.ConstantTimeCompare([]byte(user.ClientSecret), []byte(password)) != 1 {
+		return "", status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	expires := time.Now().Add(tokenValidity).Unix()
+	payload := fmt.Sprintf("%s.%d", user.ClientID, expires)
+	token := fmt.Sprintf("%s.%s", payload, signToken(payload, user.ClientSecret))
+
+	return base64.StdEncoding.EncodeToString([]byte(token)), nil
+}
+
+// IsValid checks the bearer token and returns the authenticated user as identity.
+func (v authValidator) IsValid(bearerToken string) (interface{}, error) {
+	decoded, err := base64.StdEncoding.DecodeString(bearerToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	// The client id can contain dots, the expiry time and signature can not
+	token := string(decoded)
+	signatureIndex := strings.LastIndex(token, ".")
+	if signatureIndex < 0 {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	payload, signature := token[:signatureIndex], token[signatureIndex+1:]
+
+	expiresIndex := strings.LastIndex(payload, ".")
+	if expiresIndex < 0 {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+	clientID := payload[:expiresIndex]
+	expires, err := strconv.ParseInt(payload[expiresIndex+1:], 10, 64)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	user, ok := settings.GetConfig().UsersLookup[clientID]
+	if !ok || !hmac.Equal([]byte(signature), []byte(signToken(payload, user.ClientSecret))) {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	}
+
+	if time.Now().Unix() > expires {
+		return nil, status.Error(codes.Unauthenticated, "Token expired")
+	}
+
+	return user, nil
+}
+
+// signToken returns the base64 encoded SHA-256 HMAC of the payload using the secret as key.
+func signToken(payload, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,292 @@
+package flightsql
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/flight"
+	"github.com/apache/arrow/go/v18/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/jackc/pgx/v5"
+	"github.com/sogelink-research/pgrest/api/handlers"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/service"
+	"github.com/sogelink-research/pgrest/settings"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	connectionHeader = "x-pgrest-connection" // gRPC header with the name of the connection to query
+	truncatedTrailer = "x-pgrest-truncated"  // gRPC trailer set to the limit which truncated the result, e.g. maxRows
+	batchSize        = 1000                  // Rows per record batch
+)
+
+// Server is an Arrow Flight SQL server running statement queries on the configured connections.
+// It serves the same Arrow schema and record batches as the arrow format of the query endpoint.
+type Server struct {
+	flightsql.BaseServer
+	config settings.Config // The configuration at startup, the connections are read from the current configuration
+}
+
+// statementHandle is the opaque statement handle of the ticket returned for a statement query.
+type statementHandle struct {
+	Connection string `json:"connection"`
+	Query      string `json:"query"`
+}
+
+// NewServer creates a Flight SQL server for the connections of the configuration.
+func NewServer(config settings.Config) (*Server, error) {
+	srv := &Server{config: config}
+	srv.Alloc = memory.DefaultAllocator
+
+	sqlInfo := map[flightsql.SqlInfo]interface{}{
+		flightsql.SqlInfoFlightSqlServerName:      "PGRest",
+		flightsql.SqlInfoFlightSqlServerSql:       true,
+		flightsql.SqlInfoFlightSqlServerSubstrait: false,
+	}
+	for id, value := range sqlInfo {
+		if err := srv.RegisterSqlInfo(id, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return srv, nil
+}
+
+// NewFlightServer creates the gRPC server serving the Flight SQL server on the configured port.
+// Clients authenticate using basic auth with the clientId and clientSecret of a user,
+// so the server is only served using TLS.
+func NewFlightServer(config settings.Config) (flight.Server, error) {
+	flightConfig := config.PGRest.FlightSQL
+	if flightConfig.TLSCertFile == "" || flightConfig.TLSKeyFile == "" {
+		return nil, fmt.Errorf("the Flight SQL endpoint requires tlsCertFile and tlsKeyFile, clients send their clientSecret as password")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(flightConfig.TLSCertFile, flightConfig.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading Flight SQL TLS certificate: %w", err)
+	}
+	options := []grpc.ServerOption{grpc.Creds(credentials.NewServerTLSFromCert(&certificate))}
+
+	srv, err := NewServer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	middleware := []flight.ServerMiddleware{flight.CreateServerBasicAuthMiddleware(authValidator{})}
+	server := flight.NewServerWithMiddleware(middleware, options...)
+	server.RegisterFlightService(flightsql.NewFlightServer(srv))
+
+	if err := server.Init(fmt.Sprintf(":%v", flightConfig.Port)); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// GetFlightInfoStatement returns the schema of the query result and a ticket to execute the query using DoGet.
+// The query is prepared to determine the schema, it is not executed.
+func (s *Server) GetFlightInfoStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	connection, schema, err := s.describeStatement(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := json.Marshal(statementHandle{Connection: connection.Name, Query: cmd.GetQuery()})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	ticket, err := flightsql.CreateStatementQueryTicket(handle)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &flight.FlightInfo{
+		Schema:           flight.SerializeSchema(schema, s.Alloc),
+		FlightDescriptor: desc,
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: ticket}}},
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+// GetSchemaStatement returns the schema of the query result without executing the query.
+func (s *Server) GetSchemaStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	_, schema, err := s.describeStatement(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flight.SchemaResult{Schema: flight.SerializeSchema(schema, s.Alloc)}, nil
+}
+
+// DoGetStatement executes the query of the ticket and streams the result as record batches.
+// The query is canceled when the client disconnects or the request times out.
+func (s *Server) DoGetStatement(ctx context.Context, ticket flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	var handle statementHandle
+	if err := json.Unmarshal(ticket.GetStatementHandle(), &handle); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "Invalid statement handle")
+	}
+
+	connection, user, err := s.getConnection(ctx, handle.Connection)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limits := settings.GetEffectiveLimits(connection, user)
+	options := service.NewQueryOptions(connection, user, limits)
+
+	ctx, cancel := s.requestContext(ctx)
+	rows, _, err := service.QueryPostgres(ctx, handle.Query, nil, connection, options)
+	if err != nil {
+		cancel()
+		return nil, nil, grpcError(err)
+	}
+
+	schema := handlers.NewArrowSchema(rows.FieldDescriptions())
+	chunks := make(chan flight.StreamChunk)
+
+	go func() {
+		defer cancel()
+		streamRecords(ctx, connection, rows, schema, limits, chunks)
+	}()
+
+	return schema, chunks, nil
+}
+
+// streamRecords sends the rows as record batches to the chunks channel, the rows are closed and
+// the channel is closed when done. A result truncated by a limit is reported in the truncated trailer,
+// which is sent when the stream ends.
+func streamRecords(ctx context.Context, connection *settings.ConnectionConfig, rows pgx.Rows, schema *arrow.Schema, limits settings.LimitsConfig, chunks chan<- flight.StreamChunk) {
+	defer close(chunks)
+	defer rows.Close()
+
+	truncated, err := handlers.WriteArrowRecords(ctx, rows, schema, batchSize, limits, func(record arrow.Record) error {
+		record.Retain()
+		select {
+		case chunks <- flight.StreamChunk{Data: record}:
+			return nil
+		case <-ctx.Done():
+			record.Release()
+			return ctx.Err()
+		}
+	})
+
+	if err != nil {
+		log.Errorf("Error streaming Flight SQL result on connection '%s': %v", connection.Name, err)
+		select {
+		case chunks <- flight.StreamChunk{Err: grpcError(err)}:
+		case <-ctx.Done():
+		}
+		return
+	}
+
+	if truncated != "" {
+		log.Warnf("Flight SQL result on connection '%s' truncated by %s", connection.Name, truncated)
+		if err := grpc.SetTrailer(ctx, metadata.Pairs(truncatedTrailer, truncated)); err != nil {
+			log.Errorf("Error setting Flight SQL truncated trailer: %v", err)
+		}
+	}
+}
+
+// describeStatement returns the connection and the Arrow schema of the result of the statement query.
+func (s *Server) describeStatement(ctx context.Context, cmd flightsql.StatementQuery) (*settings.ConnectionConfig, *arrow.Schema, error) {
+	if len(cmd.GetTransactionId()) > 0 {
+		return nil, nil, status.Error(codes.Unimplemented, "Transactions are not supported")
+	}
+
+	connection, user, err := s.getConnection(ctx, getConnectionName(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limits := settings.GetEffectiveLimits(connection, user)
+	options := service.NewQueryOptions(connection, user, limits)
+
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	columns, err := service.DescribeQuery(ctx, cmd.GetQuery(), connection, options)
+	if err != nil {
+		return nil, nil, grpcError(err)
+	}
+
+	return connection, handlers.NewArrowSchema(columns), nil
+}
+
+// getConnection retrieves the configuration of the connection and checks the authenticated user has access to it.
+func (s *Server) getConnection(ctx context.Context, connectionName string) (*settings.ConnectionConfig, *settings.UserConfig, error) {
+	connection, err := settings.GetConfig().GetConnectionConfig(connectionName)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.NotFound, "Requested connection '%s' not found", connectionName)
+	}
+
+	user, ok := flight.AuthFromContext(ctx).(settings.UserConfig)
+	if !ok || !user.HasConnectionAccess(connection.Name) {
+		return nil, nil, status.Error(codes.PermissionDenied, "User has not access to requested connection")
+	}
+
+	return connection, &user, nil
+}
+
+// requestContext returns a context which is canceled after the configured request timeout.
+func (s *Server) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(s.config.PGRest.Timeout)*time.Second)
+}
+
+// getConnectionName returns the connection name set in the connection header, or the default connection.
+func getConnectionName(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(connectionHeader); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return "default"
+}
+
+// grpcError converts an APIError to a gRPC status error with the matching status code.
+func grpcError(err error) error {
+	apiErr, ok := err.(*errors.APIError)
+	if !ok {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	message := apiErr.Message
+	if apiErr.Details != nil {
+		message = fmt.Sprintf("%s: %s", message, *apiErr.Details)
+	}
+
+	code := codes.Internal
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusRequestTimeout:
+		code = codes.Canceled
+	case http.StatusConflict:
+		code = codes.Aborted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	}
+
+	return status.Error(code, message)
+}
@@ -0,0 +1,97 @@
+package flightsql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow/go/v18/arrow/flight"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sogelink-research/pgrest/api/handlers"
+	"github.com/sogelink-research/pgrest/settings"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// testRows are count int4 rows, like the rows of a query decoded by pgx.
+type testRows struct {
+	count int
+	index int
+}
+
+func (r *testRows) Close()                         {}
+func (r *testRows) Err() error                     { return nil }
+func (r *testRows) CommandTag() pgconn.CommandTag  { return pgconn.CommandTag{} }
+func (r *testRows) Scan(dest ...interface{}) error { return fmt.Errorf("scan is not supported") }
+func (r *testRows) Values() ([]interface{}, error) { return []interface{}{int32(r.index)}, nil }
+func (r *testRows) RawValues() [][]byte            { return nil }
+func (r *testRows) Conn() *pgx.Conn                { return nil }
+
+func (r *testRows) FieldDescriptions() []pgconn.FieldDescription {
+	return []pgconn.FieldDescription{{Name: "id", DataTypeOID: pgtype.Int4OID}}
+}
+
+func (r *testRows) Next() bool {
+	r.index++
+	return r.index < r.count
+}
+
+// trailerStream is the server transport stream of a gRPC call, it records the trailer set by the handler.
+type trailerStream struct {
+	trailer metadata.MD
+}
+
+func (s *trailerStream) Method() string                  { return "/arrow.flight.protocol.FlightService/DoGet" }
+func (s *trailerStream) SetHeader(md metadata.MD) error  { return nil }
+func (s *trailerStream) SendHeader(md metadata.MD) error { return nil }
+
+func (s *trailerStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func TestStreamRecordsTruncated(t *testing.T) {
+	tests := []struct {
+		name          string
+		rows          int
+		limits        settings.LimitsConfig
+		wantRows      int64
+		wantTruncated []string
+	}{
+		{name: "no limits", rows: 5, wantRows: 5},
+		{name: "maxRows not reached", rows: 5, limits: settings.LimitsConfig{MaxRows: 5}, wantRows: 5},
+		{name: "maxRows", rows: 5, limits: settings.LimitsConfig{MaxRows: 3}, wantRows: 3, wantTruncated: []string{"maxRows"}},
+		// The response bytes are checked after each record batch
+		{name: "maxResponseBytes", rows: 2 * batchSize, limits: settings.LimitsConfig{MaxResponseBytes: 1}, wantRows: batchSize, wantTruncated: []string{"maxResponseBytes"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream := &trailerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+			rows := &testRows{count: test.rows, index: -1}
+			schema := handlers.NewArrowSchema(rows.FieldDescriptions())
+			chunks := make(chan flight.StreamChunk)
+			go streamRecords(ctx, &settings.ConnectionConfig{Name: "default"}, rows, schema, test.limits, chunks)
+
+			var count int64
+			for chunk := range chunks {
+				if chunk.Err != nil {
+					t.Fatalf("streamRecords returned error: %v", chunk.Err)
+				}
+				count += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+
+			if count != test.wantRows {
+				t.Errorf("streamRecords sent %d rows, want %d", count, test.wantRows)
+			}
+			if got := stream.trailer.Get(truncatedTrailer); fmt.Sprint(got) != fmt.Sprint(test.wantTruncated) {
+				t.Errorf("truncated trailer = %v, want %v", got, test.wantTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"io"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+// NewArrowSchema creates the Arrow schema of a query result with the given columns,
+// the same schema as used by the arrow format.
+func NewArrowSchema(columns []pgconn.FieldDescription) *arrow.Schema {
+	return createArrowSchema(columns, false)
+}
+
+// WriteArrowRecords converts the rows to Arrow records of at most batchSize rows using the schema
+// created by NewArrowSchema and passes each record to write, the record is released after write returns.
+// The maximum number of rows and bytes of the limits are enforced, the in-memory size of the
+// records is counted as response bytes. It returns the limit which truncated the result, e.g. "maxRows",
+// or an empty string when the result is complete.
+// It is used by the frontends serving Arrow record batches, e.g. Flight SQL.
+func WriteArrowRecords(ctx context.Context, rows pgx.Rows, schema *arrow.Schema, batchSize int, limits settings.LimitsConfig, write func(record arrow.Record) error) (string, error) {
+	counter := &countingWriter{writer: io.Discard}
+	limiter := newResultLimiter(limits, counter)
+
+	recordBuilder := createRecordBuilder(schema)
+	defer recordBuilder.Release()
+
+	err := writeArrowRecords(ctx, rows, recordBuilder, batchSize, limiter, func(record arrow.Record) error {
+		counter.written += util.TotalRecordSize(record)
+		return write(record)
+	})
+	if err != nil {
+		return "", resultError(ctx, err)
+	}
+
+	return limiter.truncated, nil
+}
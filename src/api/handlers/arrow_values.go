@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/decimal128"
+	"github.com/apache/arrow/go/v18/arrow/decimal256"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// arrowValueConverter converts a value decoded by pgx to the input of the Arrow builder of the column,
+// e.g. a pgtype.Numeric to a decimal128.Num. A nil result is appended as null.
+type arrowValueConverter func(value interface{}) (interface{}, error)
+
+// newArrowValueConverters creates the value converter for each column of the schema.
+func newArrowValueConverters(fields []pgconn.FieldDescription, schema *arrow.Schema) []arrowValueConverter {
+	converters := make([]arrowValueConverter, len(fields))
+	for i, field := range fields {
+		converters[i] = newArrowValueConverter(field.DataTypeOID, schema.Field(i).Type)
+	}
+	return converters
+}
+
+// newArrowValueConverter returns the converter for values of the PostgreSQL type with the given OID
+// to the Arrow data type the column is mapped to, see utils.PGTypeToArrowType.
+func newArrowValueConverter(oid uint32, dataType arrow.DataType) arrowValueConverter {
+	if oid == pgtype.JSONOID || oid == pgtype.JSONBOID {
+		return convertJSON
+	}
+
+	switch t := dataType.(type) {
+	case *arrow.ListType:
+		elementOID, _ := utils.PGArrayElementOID(oid)
+		return newListConverter(newArrowValueConverter(elementOID, t.Elem()))
+	case *arrow.Decimal128Type:
+		return newDecimal128Converter(t)
+	case *arrow.Decimal256Type:
+		return newDecimal256Converter(t)
+	}
+
+	switch dataType.ID() {
+	case arrow.INT16:
+		return convertInteger[int16]
+	case arrow.INT32:
+		return convertInteger[int32]
+	case arrow.INT64:
+		return convertInteger[int64]
+	case arrow.UINT32:
+		return convertInteger[uint32]
+	case arrow.FLOAT32:
+		return convertFloat32
+	case arrow.FLOAT64:
+		return convertFloat64
+	case arrow.BOOL:
+		return convertBool
+	case arrow.BINARY:
+		return convertBinary
+	case arrow.FIXED_SIZE_BINARY:
+		return convertUUID
+	case arrow.DATE32:
+		return convertDate32
+	case arrow.TIME64:
+		return convertTime64
+	case arrow.TIMESTAMP:
+		return convertTimestamp
+	case arrow.INTERVAL_MONTH_DAY_NANO:
+		return convertInterval
+	default:
+		return convertText
+	}
+}
+
+// appendArrowValues converts the values of a row and appends them to the fields of the RecordBuilder.
+func appendArrowValues(recordBuilder *array.RecordBuilder, converters []arrowValueConverter, values []interface{}) error {
+	for i, value := range values {
+		if err := appendArrowValue(recordBuilder.Field(i), converters[i], value); err != nil {
+			return fmt.Errorf("column %s: %v", recordBuilder.Schema().Field(i).Name, err)
+		}
+	}
+	return nil
+}
+
+// appendArrowValue converts a single value and appends it to the builder.
+func appendArrowValue(builder array.Builder, convert arrowValueConverter, value interface{}) error {
+	if value == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	converted, err := convert(value)
+	if err != nil {
+		return err
+	}
+	if converted == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	switch builder := builder.(type) {
+	case *array.Int16Builder:
+		builder.Append(converted.(int16))
+	case *array.Int32Builder:
+		builder.Append(converted.(int32))
+	case *array.Int64Builder:
+		builder.Append(converted.(int64))
+	case *array.Uint32Builder:
+		builder.Append(converted.(uint32))
+	case *array.Float32Builder:
+		builder.Append(converted.(float32))
+	case *array.Float64Builder:
+		builder.Append(converted.(float64))
+	case *array.BooleanBuilder:
+		builder.Append(converted.(bool))
+	case *array.StringBuilder:
+		builder.Append(converted.(string))
+	case *array.BinaryBuilder:
+		builder.Append(converted.([]byte))
+	case *array.FixedSizeBinaryBuilder:
+		builder.Append(converted.([]byte))
+	case *array.Decimal128Builder:
+		builder.Append(converted.(decimal128.Num))
+	case *array.Decimal256Builder:
+		builder.Append(converted.(decimal256.Num))
+	case *array.Date32Builder:
+		builder.Append(converted.(arrow.Date32))
+	case *array.Time64Builder:
+		builder.Append(converted.(arrow.Time64))
+	case *array.TimestampBuilder:
+		builder.Append(converted.(arrow.Timestamp))
+	case *array.MonthDayNanoIntervalBuilder:
+		builder.Append(converted.(arrow.MonthDayNanoInterval))
+	case *array.ListBuilder:
+		builder.Append(true)
+		for _, element := range converted.([]interface{}) {
+			// The elements are converted by the list converter
+			if err := appendArrowValue(builder.ValueBuilder(), convertNone, element); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported builder type %T", builder)
+	}
+	return nil
+}
+
+func convertNone(value interface{}) (interface{}, error) {
+	return value, nil
+}
+
+// newListConverter returns a converter for array values converting each element using the element converter.
+// Multidimensional arrays are decoded by pgx as a flat list of elements.
+func newListConverter(convertElement arrowValueConverter) arrowValueConverter {
+	return func(value interface{}) (interface{}, error) {
+		elements, ok := value.([]interface{})
+		if !ok {
+			return nil, unsupportedValueError(value, "list")
+		}
+
+		converted := make([]interface{}, len(elements))
+		for i, element := range elements {
+			if element == nil {
+				continue
+			}
+
+			var err error
+			if converted[i], err = convertElement(element); err != nil {
+				return nil, err
+			}
+		}
+		return converted, nil
+	}
+}
+
+// convertInteger converts any integer value to the integer type T, returning an error when the value overflows T.
+func convertInteger[T int16 | int32 | int64 | uint32](value interface{}) (interface{}, error) {
+	var v int64
+	switch i := value.(type) {
+	case int16:
+		v = int64(i)
+	case int32:
+		v = int64(i)
+	case int64:
+		v = i
+	case int:
+		v = int64(i)
+	case uint32:
+		v = int64(i)
+	default:
+		return nil, unsupportedValueError(value, "integer")
+	}
+
+	if int64(T(v)) != v {
+		return nil, fmt.Errorf("integer value %d out of range", v)
+	}
+	return T(v), nil
+}
+
+func convertFloat32(value interface{}) (interface{}, error) {
+	v, err := convertFloat64(value)
+	if err != nil {
+		return nil, err
+	}
+	return float32(v.(float64)), nil
+}
+
+func convertFloat64(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case pgtype.Numeric:
+		f, err := v.Float64Value()
+		if err != nil {
+			return nil, err
+		}
+		return f.Float64, nil
+	default:
+		return nil, unsupportedValueError(value, "float")
+	}
+}
+
+func convertBool(value interface{}) (interface{}, error) {
+	v, ok := value.(bool)
+	if !ok {
+		return nil, unsupportedValueError(value, "boolean")
+	}
+	return v, nil
+}
+
+func convertBinary(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, unsupportedValueError(value, "binary")
+	}
+}
+
+func convertUUID(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case [16]byte:
+		return v[:], nil
+	case pgtype.UUID:
+		if !v.Valid {
+			return nil, nil
+		}
+		return v.Bytes[:], nil
+	case []byte:
+		if len(v) != 16 {
+			return nil, fmt.Errorf("invalid uuid length %d", len(v))
+		}
+		return v, nil
+	default:
+		return nil, unsupportedValueError(value, "uuid")
+	}
+}
+
+// newDecimal128Converter returns a converter for numeric values to decimals with the scale of the type.
+// NaN and infinity have no decimal representation and are converted to null.
+func newDecimal128Converter(dataType *arrow.Decimal128Type) arrowValueConverter {
+	return func(value interface{}) (interface{}, error) {
+		unscaled, err := unscaledNumeric(value, dataType.Precision, dataType.Scale)
+		if unscaled == nil || err != nil {
+			return nil, err
+		}
+		return decimal128.FromBigInt(unscaled), nil
+	}
+}
+
+// newDecimal256Converter returns a converter for numeric values to decimals with the scale of the type.
+// NaN and infinity have no decimal representation and are converted to null.
+func newDecimal256Converter(dataType *arrow.Decimal256Type) arrowValueConverter {
+	return func(value interface{}) (interface{}, error) {
+		unscaled, err := unscaledNumeric(value, dataType.Precision, dataType.Scale)
+		if unscaled == nil || err != nil {
+			return nil, err
+		}
+		return decimal256.FromBigInt(unscaled), nil
+	}
+}
+
+// unscaledNumeric returns the integer value of the numeric multiplied by 10^scale, rounded half away from zero.
+// It returns an error when the value does not fit the precision and nil for NaN and infinity.
+func unscaledNumeric(value interface{}, precision int32, scale int32) (*big.Int, error) {
+	numeric, ok := value.(pgtype.Numeric)
+	if !ok {
+		return nil, unsupportedValueError(value, "numeric")
+	}
+	if !numeric.Valid || numeric.NaN || numeric.InfinityModifier != pgtype.Finite {
+		return nil, nil
+	}
+
+	exp := numeric.Exp + scale
+	unscaled := new(big.Int).Set(numeric.Int)
+	if exp >= 0 {
+		unscaled.Mul(unscaled, pow10(exp))
+	} else {
+		divisor := pow10(-exp)
+		remainder := new(big.Int)
+		unscaled.QuoRem(unscaled, divisor, remainder)
+		if remainder.Abs(remainder).Lsh(remainder, 1).Cmp(divisor) >= 0 {
+			unscaled.Add(unscaled, big.NewInt(int64(numeric.Int.Sign())))
+		}
+	}
+
+	if new(big.Int).Abs(unscaled).Cmp(pow10(precision)) >= 0 {
+		return nil, fmt.Errorf("numeric value exceeds precision %d", precision)
+	}
+	return unscaled, nil
+}
+
+func pow10(exp int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)
+}
+
+// convertDate32 converts dates to days since the UNIX epoch, infinite dates are converted to null.
+func convertDate32(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return arrow.Date32FromTime(v), nil
+	case pgtype.Date:
+		if !v.Valid || v.InfinityModifier != pgtype.Finite {
+			return nil, nil
+		}
+		return arrow.Date32FromTime(v.Time), nil
+	case string, pgtype.InfinityModifier:
+		return nil, nil
+	default:
+		return nil, unsupportedValueError(value, "date")
+	}
+}
+
+// convertTime64 converts times to microseconds since midnight.
+func convertTime64(value interface{}) (interface{}, error) {
+	v, ok := value.(pgtype.Time)
+	if !ok {
+		return nil, unsupportedValueError(value, "time")
+	}
+	if !v.Valid {
+		return nil, nil
+	}
+	return arrow.Time64(v.Microseconds), nil
+}
+
+// convertTimestamp converts timestamps to microseconds since the UNIX epoch, infinite timestamps are converted to null.
+// Timestamps without time zone are decoded by pgx as UTC, so their wall clock time is kept.
+func convertTimestamp(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return arrow.Timestamp(v.UnixMicro()), nil
+	case pgtype.Timestamp:
+		if !v.Valid || v.InfinityModifier != pgtype.Finite {
+			return nil, nil
+		}
+		return arrow.Timestamp(v.Time.UnixMicro()), nil
+	case pgtype.Timestamptz:
+		if !v.Valid || v.InfinityModifier != pgtype.Finite {
+			return nil, nil
+		}
+		return arrow.Timestamp(v.Time.UnixMicro()), nil
+	case string, pgtype.InfinityModifier:
+		return nil, nil
+	default:
+		return nil, unsupportedValueError(value, "timestamp")
+	}
+}
+
+func convertInterval(value interface{}) (interface{}, error) {
+	v, ok := value.(pgtype.Interval)
+	if !ok {
+		return nil, unsupportedValueError(value, "interval")
+	}
+	if !v.Valid {
+		return nil, nil
+	}
+	if v.Microseconds > math.MaxInt64/int64(time.Microsecond) || v.Microseconds < math.MinInt64/int64(time.Microsecond) {
+		return nil, fmt.Errorf("interval value out of range")
+	}
+	return arrow.MonthDayNanoInterval{Months: v.Months, Days: v.Days, Nanoseconds: v.Microseconds * int64(time.Microsecond)}, nil
+}
+
+// convertText returns the text representation of values mapped to an Arrow string,
+// e.g. JSON values, network addresses, intervals and types without Arrow equivalent.
+func convertText(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case rune: // "char"
+		return string(v), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case driver.Valuer:
+		text, err := v.Value()
+		if err != nil || text == nil {
+			return nil, err
+		}
+		if str, ok := text.(string); ok {
+			return str, nil
+		}
+		return fmt.Sprint(text), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+}
+
+// convertJSON converts JSON values decoded by pgx back to their JSON text.
+func convertJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func unsupportedValueError(value interface{}, target string) error {
+	return fmt.Errorf("unsupported value of type %T for %s", value, target)
+}
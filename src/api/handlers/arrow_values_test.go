@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/decimal128"
+	"github.com/apache/arrow/go/v18/arrow/decimal256"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/apache/arrow/go/v18/parquet"
+	"github.com/apache/arrow/go/v18/parquet/file"
+	"github.com/apache/arrow/go/v18/parquet/pqarrow"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// numericTypeModifier returns the type modifier of numeric(precision, scale).
+func numericTypeModifier(precision, scale int32) int32 {
+	return (precision<<16 | scale) + 4
+}
+
+// numeric returns the pgx value of the numeric unscaled * 10^exp.
+func numeric(unscaled int64, exp int32) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(unscaled), Exp: exp, Valid: true}
+}
+
+// arrowValueTests are values as decoded by pgx with the result of their arrowValueConverter,
+// a nil result is appended as null.
+var arrowValueTests = []struct {
+	name         string
+	oid          uint32
+	typeModifier int32
+	value        interface{}
+	want         interface{}
+	wantErr      bool
+}{
+	{name: "int2", oid: pgtype.Int2OID, value: int16(-32768), want: int16(-32768)},
+	{name: "int4", oid: pgtype.Int4OID, value: int32(math.MaxInt32), want: int32(math.MaxInt32)},
+	{name: "int4 overflow", oid: pgtype.Int4OID, value: int64(math.MaxInt32 + 1), wantErr: true},
+	{name: "int8", oid: pgtype.Int8OID, value: int64(math.MinInt64), want: int64(math.MinInt64)},
+	{name: "oid", oid: pgtype.OIDOID, value: uint32(math.MaxUint32), want: uint32(math.MaxUint32)},
+	{name: "float4", oid: pgtype.Float4OID, value: float32(1.5), want: float32(1.5)},
+	{name: "float8", oid: pgtype.Float8OID, value: 2.25, want: 2.25},
+	{name: "float8 infinity", oid: pgtype.Float8OID, value: math.Inf(1), want: math.Inf(1)},
+	{name: "bool", oid: pgtype.BoolOID, value: true, want: true},
+	{name: "text", oid: pgtype.TextOID, value: "pgrest", want: "pgrest"},
+	{name: "numeric", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(10, 2), value: numeric(1234, -2), want: decimal128.FromI64(1234)},
+	{name: "numeric scaled up", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(10, 2), value: numeric(12, 1), want: decimal128.FromI64(12000)},
+	{name: "numeric rounded up", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(10, 2), value: numeric(12345, -3), want: decimal128.FromI64(1235)},
+	{name: "numeric rounded down", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(10, 2), value: numeric(12344, -3), want: decimal128.FromI64(1234)},
+	{name: "numeric negative rounded away from zero", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(10, 2), value: numeric(-12345, -3), want: decimal128.FromI64(-1235)},
+	{name: "numeric exceeds precision", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(5, 2), value: numeric(12345, -1), wantErr: true},
+	{name: "numeric rounded exceeds precision", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(3, 2), value: numeric(9999, -3), wantErr: true},
+	{name: "numeric NaN", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(10, 2), value: pgtype.Numeric{NaN: true, Valid: true}, want: nil},
+	{name: "numeric infinity", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(10, 2), value: pgtype.Numeric{InfinityModifier: pgtype.Infinity, Valid: true}, want: nil},
+	{name: "numeric decimal256", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(50, 5), value: numeric(-123456789, -2), want: decimal256.FromI64(-123456789000)},
+	{name: "numeric unconstrained", oid: pgtype.NumericOID, typeModifier: -1, value: numeric(12345, -3), want: "12.345"},
+	{name: "date", oid: pgtype.DateOID, value: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), want: arrow.Date32(19782)},
+	{name: "date before epoch", oid: pgtype.DateOID, value: time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC), want: arrow.Date32(-1)},
+	{name: "date infinity", oid: pgtype.DateOID, value: pgtype.Infinity, want: nil},
+	{name: "timestamp", oid: pgtype.TimestampOID, value: time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC), want: arrow.Timestamp(1704164645000006)},
+	{name: "timestamp negative infinity", oid: pgtype.TimestampOID, value: pgtype.NegativeInfinity, want: nil},
+	{name: "timestamptz", oid: pgtype.TimestamptzOID, value: time.Date(2024, 1, 2, 4, 4, 5, 6000, time.FixedZone("CET", 3600)), want: arrow.Timestamp(1704164645000006)},
+	{name: "timestamptz infinity", oid: pgtype.TimestamptzOID, value: pgtype.Infinity, want: nil},
+	{name: "time", oid: pgtype.TimeOID, value: pgtype.Time{Microseconds: 86399999999, Valid: true}, want: arrow.Time64(86399999999)},
+	{name: "interval", oid: pgtype.IntervalOID, value: pgtype.Interval{Months: 14, Days: -3, Microseconds: 1500, Valid: true}, want: arrow.MonthDayNanoInterval{Months: 14, Days: -3, Nanoseconds: 1500000}},
+	{name: "interval out of range", oid: pgtype.IntervalOID, value: pgtype.Interval{Microseconds: math.MaxInt64, Valid: true}, wantErr: true},
+	{name: "uuid", oid: pgtype.UUIDOID, value: [16]byte{0x12, 0x34, 15: 0xff}, want: []byte{0x12, 0x34, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff}},
+	{name: "bytea", oid: pgtype.ByteaOID, value: []byte{0, 1, 2}, want: []byte{0, 1, 2}},
+	{name: "json", oid: pgtype.JSONOID, value: map[string]interface{}{"a": []interface{}{1.5, "b", nil}}, want: `{"a":[1.5,"b",null]}`},
+	{name: "jsonb string", oid: pgtype.JSONBOID, value: "text", want: `"text"`},
+	{name: "int4 array", oid: pgtype.Int4ArrayOID, value: []interface{}{int32(1), nil, int32(3)}, want: []interface{}{int32(1), nil, int32(3)}},
+	{name: "empty text array", oid: pgtype.TextArrayOID, value: []interface{}{}, want: []interface{}{}},
+	{name: "numeric array", oid: pgtype.NumericArrayOID, typeModifier: numericTypeModifier(4, 1), value: []interface{}{numeric(125, -2), pgtype.Numeric{NaN: true, Valid: true}}, want: []interface{}{decimal128.FromI64(13), nil}},
+	{name: "timestamptz array infinity", oid: pgtype.TimestamptzArrayOID, value: []interface{}{pgtype.Infinity, time.UnixMicro(1)}, want: []interface{}{nil, arrow.Timestamp(1)}},
+	{name: "int2 array overflow", oid: pgtype.Int2ArrayOID, value: []interface{}{int32(40000)}, wantErr: true},
+	{name: "unsupported value", oid: pgtype.Int4OID, value: "1", wantErr: true},
+}
+
+func TestArrowValueConverter(t *testing.T) {
+	for _, test := range arrowValueTests {
+		t.Run(test.name, func(t *testing.T) {
+			convert := newArrowValueConverter(test.oid, utils.PGTypeToArrowType(test.oid, test.typeModifier))
+
+			got, err := convert(test.value)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("convert(%v) = %v, want error", test.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convert(%v) returned error: %v", test.value, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("convert(%v) = %#v, want %#v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAppendArrowValues(t *testing.T) {
+	for _, test := range arrowValueTests {
+		t.Run(test.name, func(t *testing.T) {
+			fields := []pgconn.FieldDescription{{Name: "value", DataTypeOID: test.oid, TypeModifier: test.typeModifier}}
+			schema := createArrowSchema(fields, false)
+			recordBuilder := createRecordBuilder(schema)
+			defer recordBuilder.Release()
+
+			err := appendArrowValues(recordBuilder, newArrowValueConverters(fields, schema), []interface{}{test.value})
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("appendArrowValues returned no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("appendArrowValues returned error: %v", err)
+			}
+
+			record := recordBuilder.NewRecord()
+			defer record.Release()
+
+			column := record.Column(0)
+			if column.Len() != 1 {
+				t.Fatalf("got %d values, want 1", column.Len())
+			}
+			if got, want := column.IsNull(0), test.want == nil; got != want {
+				t.Errorf("null = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAppendArrowValuesNull(t *testing.T) {
+	fields := []pgconn.FieldDescription{
+		{Name: "int", DataTypeOID: pgtype.Int4OID},
+		{Name: "numeric", DataTypeOID: pgtype.NumericOID, TypeModifier: numericTypeModifier(10, 2)},
+		{Name: "list", DataTypeOID: pgtype.Int4ArrayOID},
+	}
+	schema := createArrowSchema(fields, false)
+	recordBuilder := createRecordBuilder(schema)
+	defer recordBuilder.Release()
+
+	if err := appendArrowValues(recordBuilder, newArrowValueConverters(fields, schema), []interface{}{nil, nil, nil}); err != nil {
+		t.Fatalf("appendArrowValues returned error: %v", err)
+	}
+
+	record := recordBuilder.NewRecord()
+	defer record.Release()
+
+	for i, column := range record.Columns() {
+		if !column.IsNull(0) {
+			t.Errorf("column %s is not null", fields[i].Name)
+		}
+	}
+}
+
+// roundTripColumns are the columns of the rows written in the Arrow and Parquet formats.
+var roundTripColumns = []pgconn.FieldDescription{
+	{Name: "int2", DataTypeOID: pgtype.Int2OID},
+	{Name: "int4", DataTypeOID: pgtype.Int4OID},
+	{Name: "int8", DataTypeOID: pgtype.Int8OID},
+	{Name: "oid", DataTypeOID: pgtype.OIDOID},
+	{Name: "float4", DataTypeOID: pgtype.Float4OID},
+	{Name: "float8", DataTypeOID: pgtype.Float8OID},
+	{Name: "bool", DataTypeOID: pgtype.BoolOID},
+	{Name: "text", DataTypeOID: pgtype.TextOID},
+	{Name: "numeric", DataTypeOID: pgtype.NumericOID, TypeModifier: numericTypeModifier(10, 2)},
+	{Name: "numeric256", DataTypeOID: pgtype.NumericOID, TypeModifier: numericTypeModifier(50, 5)},
+	{Name: "numeric_text", DataTypeOID: pgtype.NumericOID, TypeModifier: -1},
+	{Name: "date", DataTypeOID: pgtype.DateOID},
+	{Name: "timestamp", DataTypeOID: pgtype.TimestampOID},
+	{Name: "timestamptz", DataTypeOID: pgtype.TimestamptzOID},
+	{Name: "time", DataTypeOID: pgtype.TimeOID},
+	{Name: "interval", DataTypeOID: pgtype.IntervalOID},
+	{Name: "uuid", DataTypeOID: pgtype.UUIDOID},
+	{Name: "bytea", DataTypeOID: pgtype.ByteaOID},
+	{Name: "json", DataTypeOID: pgtype.JSONBOID},
+	{Name: "int4_array", DataTypeOID: pgtype.Int4ArrayOID},
+	{Name: "text_array", DataTypeOID: pgtype.TextArrayOID},
+}
+
+// roundTripValues are the rows written in the Arrow and Parquet formats, as decoded by pgx.
+var roundTripValues = [][]interface{}{
+	{
+		int16(1), int32(2), int64(3), uint32(4), float32(5.5), 6.25, true, "seven",
+		numeric(899, -2), numeric(-123456789, -2), numeric(12345, -3),
+		time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC),
+		time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC),
+		pgtype.Time{Microseconds: 3723000000, Valid: true},
+		pgtype.Interval{Months: 1, Days: 2, Microseconds: 3, Valid: true},
+		[16]byte{1, 2, 3, 15: 4},
+		[]byte("bytes"),
+		map[string]interface{}{"a": 1.0},
+		[]interface{}{int32(1), nil, int32(3)},
+		[]interface{}{"a", "b"},
+	},
+	{
+		nil, nil, nil, nil, nil, nil, nil, nil,
+		pgtype.Numeric{NaN: true, Valid: true}, nil, nil,
+		pgtype.Infinity,
+		pgtype.NegativeInfinity,
+		pgtype.Infinity,
+		nil, nil, nil, nil, nil,
+		[]interface{}{},
+		nil,
+	},
+}
+
+// testRows are rows with the given columns and values, like the rows of a query decoded by pgx.
+type testRows struct {
+	fields []pgconn.FieldDescription
+	values [][]interface{}
+	index  int
+}
+
+func newTestRows(fields []pgconn.FieldDescription, values [][]interface{}) *testRows {
+	return &testRows{fields: fields, values: values, index: -1}
+}
+
+func (r *testRows) Close()                                       {}
+func (r *testRows) Err() error                                   { return nil }
+func (r *testRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *testRows) FieldDescriptions() []pgconn.FieldDescription { return r.fields }
+func (r *testRows) Scan(dest ...interface{}) error               { return fmt.Errorf("scan is not supported") }
+func (r *testRows) Values() ([]interface{}, error)               { return r.values[r.index], nil }
+func (r *testRows) RawValues() [][]byte                          { return nil }
+func (r *testRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *testRows) Next() bool {
+	if r.index+1 >= len(r.values) {
+		return false
+	}
+	r.index++
+	return true
+}
+
+// expectedRecord returns the record of the round trip values appended with the converters for the schema.
+func expectedRecord(t *testing.T, schema *arrow.Schema) arrow.Record {
+	t.Helper()
+
+	recordBuilder := createRecordBuilder(schema)
+	defer recordBuilder.Release()
+
+	converters := newArrowValueConverters(roundTripColumns, schema)
+	for _, values := range roundTripValues {
+		if err := appendArrowValues(recordBuilder, converters, values); err != nil {
+			t.Fatalf("appendArrowValues returned error: %v", err)
+		}
+	}
+	return recordBuilder.NewRecord()
+}
+
+// assertColumnsEqual checks the columns of the record have the values of the expected record.
+func assertColumnsEqual(t *testing.T, expected arrow.Record, got arrow.Record) {
+	t.Helper()
+
+	if got.NumRows() != expected.NumRows() || got.NumCols() != expected.NumCols() {
+		t.Fatalf("got %d rows and %d columns, want %d rows and %d columns", got.NumRows(), got.NumCols(), expected.NumRows(), expected.NumCols())
+	}
+
+	for i := range expected.Columns() {
+		name := expected.ColumnName(i)
+		if got.ColumnName(i) != name {
+			t.Errorf("column %d is named %s, want %s", i, got.ColumnName(i), name)
+		}
+		for row := 0; row < int(expected.NumRows()); row++ {
+			want, value := expected.Column(i).ValueStr(row), got.Column(i).ValueStr(row)
+			if value != want {
+				t.Errorf("column %s row %d = %s, want %s", name, row, value, want)
+			}
+		}
+	}
+}
+
+func TestHandleFormatArrowRoundTrip(t *testing.T) {
+	var buffer bytes.Buffer
+	limiter := newResultLimiter(settings.LimitsConfig{}, nil)
+	rows := newTestRows(roundTripColumns, roundTripValues)
+
+	if err := handleFormatArrow(context.Background(), httptest.NewRecorder(), rows, &buffer, 1, limiter); err != nil {
+		t.Fatalf("handleFormatArrow returned error: %v", err)
+	}
+
+	reader, err := ipc.NewReader(&buffer)
+	if err != nil {
+		t.Fatalf("reading the Arrow stream: %v", err)
+	}
+	defer reader.Release()
+
+	schema := createArrowSchema(roundTripColumns, false)
+	if !reader.Schema().Equal(schema) {
+		t.Fatalf("schema = %s, want %s", reader.Schema(), schema)
+	}
+
+	// One record batch per row
+	var records []arrow.Record
+	for reader.Next() {
+		record := reader.Record()
+		record.Retain()
+		defer record.Release()
+		records = append(records, record)
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatalf("reading the Arrow stream: %v", err)
+	}
+
+	table := array.NewTableFromRecords(schema, records)
+	defer table.Release()
+	got := tableRecord(t, table)
+	defer got.Release()
+
+	expected := expectedRecord(t, schema)
+	defer expected.Release()
+
+	for i := range expected.Columns() {
+		if !array.Equal(expected.Column(i), got.Column(i)) {
+			t.Errorf("column %s = %s, want %s", expected.ColumnName(i), got.Column(i), expected.Column(i))
+		}
+	}
+}
+
+// TestHandleFormatParquetRoundTrip reads the Parquet file written with each compression codec using pqarrow,
+// which checks the registered LZ4_RAW codec against the reader.
+func TestHandleFormatParquetRoundTrip(t *testing.T) {
+	for compression, codec := range parquetCompressions {
+		t.Run(string(compression), func(t *testing.T) {
+			var buffer bytes.Buffer
+			limiter := newResultLimiter(settings.LimitsConfig{}, nil)
+			rows := newTestRows(roundTripColumns, roundTripValues)
+			options := models.FormatOptions{RowGroupSize: 1, Compression: compression}
+
+			if err := handleFormatParquet(context.Background(), httptest.NewRecorder(), rows, &buffer, limiter, options); err != nil {
+				t.Fatalf("handleFormatParquet returned error: %v", err)
+			}
+
+			reader, err := file.NewParquetReader(bytes.NewReader(buffer.Bytes()))
+			if err != nil {
+				t.Fatalf("opening the Parquet file: %v", err)
+			}
+			column, err := reader.MetaData().RowGroup(0).ColumnChunk(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if column.Compression() != codec {
+				t.Errorf("column chunk compression = %s, want %s", column.Compression(), codec)
+			}
+			reader.Close()
+
+			table, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buffer.Bytes()), parquet.NewReaderProperties(nil), pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+			if err != nil {
+				t.Fatalf("reading the Parquet file: %v", err)
+			}
+			defer table.Release()
+
+			got := tableRecord(t, table)
+			defer got.Release()
+
+			schema := createArrowSchema(roundTripColumns, true)
+			expected := expectedRecord(t, schema)
+			defer expected.Release()
+
+			for i, field := range schema.Fields() {
+				gotType := got.Schema().Field(i).Type
+				if field.Type.ID() != gotType.ID() {
+					t.Errorf("column %s has type %s, want %s", field.Name, gotType, field.Type)
+				}
+			}
+			assertColumnsEqual(t, expected, got)
+		})
+	}
+}
+
+// tableRecord returns the rows of the table as a single record, concatenating the chunks of the columns.
+func tableRecord(t *testing.T, table arrow.Table) arrow.Record {
+	t.Helper()
+
+	columns := make([]arrow.Array, table.NumCols())
+	for i := range columns {
+		column, err := array.Concatenate(table.Column(i).Data().Chunks(), memory.DefaultAllocator)
+		if err != nil {
+			t.Fatalf("concatenating column %s: %v", table.Column(i).Name(), err)
+		}
+		defer column.Release()
+		columns[i] = column
+	}
+	return array.NewRecord(table.Schema(), columns, table.NumRows())
+}
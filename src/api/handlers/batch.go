@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/service"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// BatchHandler handles the HTTP request for executing a batch of statements in one transaction.
+// The statements run in order on the same snapshot of the database, the response contains the result
+// of each statement in its requested JSON format. When a statement fails the transaction is rolled back
+// and the error of the failed statement is returned.
+// The response is buffered until the transaction is committed, the maxRows limit applies to each
+// statement and the maxResponseBytes limit to the whole response.
+// Batches are not allowed on connections with row-level security, see adHocSQLError.
+func BatchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		connection, err := getConnection(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		if connection.RowLevelSecurity {
+			HandleError(w, adHocSQLError(connection, "in a batch"))
+			return
+		}
+
+		var body models.BatchRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			details := err.Error()
+			HandleError(w, errors.NewAPIError(http.StatusBadRequest, "Invalid request body", &details))
+			return
+		}
+
+		ctx := r.Context()
+		defer logContextDone(ctx, connection)
+
+		user := utils.GetUserFromRequest(r)
+		limits := settings.GetEffectiveLimits(connection, user)
+		setLimitHeaders(w, limits)
+
+		statements := make([]service.BatchStatement, len(body.Statements))
+		for i, statement := range body.Statements {
+			statements[i] = service.BatchStatement{Query: statement.Query, Args: statement.Args()}
+		}
+
+		var buffer bytes.Buffer
+		counter := &countingWriter{writer: &buffer}
+		encoder := json.NewEncoder(counter)
+		truncated := ""
+
+		counter.Write([]byte(`{"results":[`))
+		options := service.NewQueryOptions(connection, user, limits)
+		err = service.QueryBatch(ctx, statements, connection, options, func(i int, rows pgx.Rows, columns []string) error {
+			if i > 0 {
+				counter.Write([]byte(`,`))
+			}
+
+			limiter := newResultLimiter(limits, counter)
+			statement := body.Statements[i]
+
+			var err error
+			switch statement.Format {
+			case models.JSONDataArrayFormat:
+				err = handleFormatJSONDataArray(ctx, w, rows, columns, counter, encoder, limiter)
+			case models.GeoJSONFormat:
+				err = handleFormatGeoJSON(ctx, w, rows, connection, counter, encoder, limiter, statement.FormatOptions)
+			default:
+				err = handleFormatJSON(ctx, w, rows, columns, counter, encoder, limiter)
+			}
+
+			if limiter.truncated != "" {
+				truncated = limiter.truncated
+			}
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				err = contextError(ctx.Err())
+			}
+			HandleError(w, err)
+			return
+		}
+		counter.Write([]byte(`]}`))
+
+		// The formats set their own content type, the batch response is one JSON document
+		w.Header().Set("Content-Type", "application/json")
+		if truncated != "" {
+			w.Header().Set(truncatedTrailer, truncated)
+		}
+
+		bw := bufio.NewWriterSize(w, 64*1024)
+		compressionWriter, closeWriter := newCompressionWriter(w, r, bw)
+		buffer.WriteTo(compressionWriter)
+		closeWriter()
+		bw.Flush()
+	}
+}
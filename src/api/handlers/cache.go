@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sogelink-research/pgrest/cache"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+const cacheHeader = "X-Cache" // Header set to HIT when the response is served from the result cache, MISS otherwise
+
+// resultCache captures the uncompressed response body of a query result to store it in the result cache.
+// Capturing stops when the result exceeds the maximum size of a cached result.
+type resultCache struct {
+	key      string
+	ttl      time.Duration
+	buffer   bytes.Buffer
+	overflow bool
+}
+
+func (c *resultCache) Write(p []byte) (int, error) {
+	if c.overflow {
+		return len(p), nil
+	}
+
+	if int64(c.buffer.Len()+len(p)) > cache.MaxEntryBytes() {
+		c.overflow = true
+		c.buffer = bytes.Buffer{}
+		return len(p), nil
+	}
+
+	return c.buffer.Write(p)
+}
+
+// store caches the captured result with the content type set by the format handler.
+// The ETag of the cached result is sent as trailer, as the response body has already been written.
+func (c *resultCache) store(w http.ResponseWriter, truncated string) {
+	if c.overflow {
+		return
+	}
+
+	if etag := cache.Put(c.key, c.buffer.Bytes(), w.Header().Get("Content-Type"), truncated, c.ttl); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// getResultCache returns the resultCache to capture the result of the query for the cache key of the request,
+// or nil when the result should not be cached. When the result is cached it is written as response and true is returned.
+// Results of connections with row-level security are cached per user.
+// The Cache-Control request header is honored, no-cache skips the cached result and no-store does not cache the result.
+// Responses of cacheable results vary by the Accept-Encoding header.
+func getResultCache(w http.ResponseWriter, r *http.Request, ttlSeconds int, connection *settings.ConnectionConfig, query string, args []interface{}, format models.FormatType, formatOptions models.FormatOptions, limits settings.LimitsConfig) (*resultCache, bool) {
+	if ttlSeconds <= 0 {
+		return nil, false
+	}
+
+	// The cached result and its ETag are the same for every encoding, so shared caches must not mix the encodings
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	parts := []interface{}{connection.Name, query, format, formatOptions, limits}
+	if connection.RowLevelSecurity {
+		// The result depends on the role and claims of the user
+		parts = append(parts, getClientID(r))
+	}
+	key := cache.Key(append(parts, args...)...)
+
+	noCache, noStore := getCacheControl(r)
+	if !noCache && writeCachedResult(w, r, key) {
+		return nil, true
+	}
+
+	w.Header().Set(cacheHeader, "MISS")
+	if noStore {
+		return nil, false
+	}
+
+	// The ETag is only known when the result is stored, after the response body is written
+	w.Header().Add("Trailer", "ETag")
+	return &resultCache{key: key, ttl: time.Duration(ttlSeconds) * time.Second}, false
+}
+
+// writeCachedResult writes the cached result with the given key, compressed based on the Accept-Encoding header.
+// When the If-None-Match header matches the ETag of the result a 304 Not Modified response is written.
+// A truncated result reports the limit in the truncated trailer, which must be announced before.
+// It returns false when the result is not cached.
+func writeCachedResult(w http.ResponseWriter, r *http.Request, key string) bool {
+	entry, ok := cache.Get(key)
+	if !ok {
+		return false
+	}
+
+	data, err := entry.Open()
+	if err != nil {
+		return false
+	}
+	defer data.Close()
+
+	w.Header().Set(cacheHeader, "HIT")
+	w.Header().Set("ETag", entry.ETag)
+	w.Header().Set("Age", strconv.Itoa(int(entry.Age().Seconds())))
+
+	if etagMatches(r.Header.Get("If-None-Match"), entry.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	w.Header().Set("Content-Type", entry.ContentType)
+
+	bw := bufio.NewWriterSize(w, 64*1024)
+	compressionWriter, closeWriter := newCompressionWriter(w, r, bw)
+	io.Copy(compressionWriter, data)
+	closeWriter()
+	bw.Flush()
+
+	// Sent as the trailer announced by setLimitHeaders, like when the result is streamed from the database
+	if entry.Truncated != "" {
+		w.Header().Set(truncatedTrailer, entry.Truncated)
+	}
+
+	return true
+}
+
+// getCacheControl returns whether the no-cache and no-store directives are set in the Cache-Control request header.
+func getCacheControl(r *http.Request) (noCache bool, noStore bool) {
+	for _, value := range r.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "no-cache":
+				noCache = true
+			case "no-store":
+				noStore = true
+			}
+		}
+	}
+	return noCache, noStore
+}
+
+// etagMatches checks if the If-None-Match header matches the ETag using the weak comparison.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
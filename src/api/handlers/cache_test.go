@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sogelink-research/pgrest/cache"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+// newCacheTestServer returns a server responding with a cacheable result, like writeQueryResult.
+// When truncated is set the result is reported as truncated by that limit.
+func newCacheTestServer(t *testing.T, truncated string) *httptest.Server {
+	t.Helper()
+
+	if err := cache.Init(settings.ResultCacheConfig{MaxBytes: 1024, MaxEntryBytes: 1024}); err != nil {
+		t.Fatal(err)
+	}
+
+	connection := &settings.ConnectionConfig{Name: "default"}
+	limits := settings.LimitsConfig{MaxRows: 1}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setLimitHeaders(w, limits)
+		resultCache, handled := getResultCache(w, r, 60, connection, "SELECT 1", nil, models.JSONFormat, models.FormatOptions{}, limits)
+		if handled {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(io.MultiWriter(w, resultCache), `[{"?column?":1}]`)
+		if truncated != "" {
+			w.Header().Set(truncatedTrailer, truncated)
+		}
+		resultCache.store(w, truncated)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// getCacheTestServer requests the result of the server, the body is read so the trailers are available.
+func getCacheTestServer(t *testing.T, server *httptest.Server, ifNoneMatch string) *http.Response {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	return res
+}
+
+func TestResultCacheETag(t *testing.T) {
+	server := newCacheTestServer(t, "")
+
+	miss := getCacheTestServer(t, server, "")
+	etag := miss.Trailer.Get("ETag")
+	if miss.Header.Get(cacheHeader) != "MISS" || etag == "" {
+		t.Fatalf("first response: X-Cache %q, ETag trailer %q, want MISS and an ETag", miss.Header.Get(cacheHeader), etag)
+	}
+	if miss.Header.Get("ETag") != "" {
+		t.Errorf("first response has ETag header %q, want it only as trailer", miss.Header.Get("ETag"))
+	}
+
+	hit := getCacheTestServer(t, server, "")
+	if hit.Header.Get(cacheHeader) != "HIT" || hit.Header.Get("ETag") != etag {
+		t.Errorf("second response: X-Cache %q, ETag %q, want HIT and %q", hit.Header.Get(cacheHeader), hit.Header.Get("ETag"), etag)
+	}
+
+	notModified := getCacheTestServer(t, server, etag)
+	if notModified.StatusCode != http.StatusNotModified {
+		t.Errorf("response with If-None-Match %s: status %d, want %d", etag, notModified.StatusCode, http.StatusNotModified)
+	}
+
+	for name, res := range map[string]*http.Response{"MISS": miss, "HIT": hit, "304": notModified} {
+		if vary := res.Header.Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("%s response has Vary %q, want Accept-Encoding", name, vary)
+		}
+	}
+}
+
+func TestResultCacheTruncated(t *testing.T) {
+	tests := []struct {
+		name      string
+		truncated string
+	}{
+		{name: "complete", truncated: ""},
+		{name: "truncated", truncated: truncatedMaxRows},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newCacheTestServer(t, test.truncated)
+
+			for _, want := range []string{"MISS", "HIT"} {
+				res := getCacheTestServer(t, server, "")
+				if got := res.Header.Get(cacheHeader); got != want {
+					t.Fatalf("X-Cache = %q, want %q", got, want)
+				}
+				if got := res.Trailer.Get(truncatedTrailer); got != test.truncated {
+					t.Errorf("%s: truncated trailer = %q, want %q", want, got, test.truncated)
+				}
+				if got := res.Header.Get(truncatedTrailer); got != "" {
+					t.Errorf("%s: truncated header = %q, want it only as trailer", want, got)
+				}
+			}
+		})
+	}
+}
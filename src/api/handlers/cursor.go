@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/service"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/transactions"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+const cursorHeader = "X-PGRest-Cursor" // Header set to the continuation token when a paged result has a next page
+
+// CursorCloseHandler handles the HTTP request for closing a cursor before its last page is fetched,
+// rolling back its transaction and returning its connection to the pool.
+func CursorCloseHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		connection, err := getConnection(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		token, err := utils.GetCursorFromRequest(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		t, err := transactions.Acquire(token, transactions.KindCursor, connection.Name, getClientID(r))
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+		defer t.Release()
+
+		t.Close(context.Background(), false)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeQueryPage writes a page of the query result in the requested format. When paging sets a page size
+// a cursor is opened for the query and the first page is written, when paging sets a cursor token the next
+// page of that cursor is written. The token of the next page is set in the X-PGRest-Cursor header, the cursor
+// is closed after its last page. The page size is capped at the maximum page size. The maxRows limit applies to
+// all pages together, when it is reached the cursor is closed and the page has the X-PGRest-Truncated trailer.
+func writeQueryPage(w http.ResponseWriter, r *http.Request, config settings.Config, connection *settings.ConnectionConfig, query string, args []interface{}, format models.FormatType, formatOptions models.FormatOptions, paging models.PagingOptions) {
+	ctx := r.Context()
+	defer logContextDone(ctx, connection)
+
+	clientID := getClientID(r)
+	user := utils.GetUserFromRequest(r)
+	limits := settings.GetEffectiveLimits(connection, user)
+	setLimitHeaders(w, limits)
+
+	var t *transactions.Transaction
+	var err error
+	if paging.Cursor != "" {
+		t, err = transactions.Acquire(paging.Cursor, transactions.KindCursor, connection.Name, clientID)
+	} else {
+		cursorsConfig := config.PGRest.Cursors
+		pageSize := min(paging.PageSize, cursorsConfig.MaxPageSize)
+		if limits.MaxRows > 0 {
+			pageSize = min(pageSize, limits.MaxRows)
+		}
+
+		cursorLimits := transactions.Limits{
+			MaxPerUser:  cursorsConfig.MaxPerUser,
+			IdleTimeout: time.Duration(cursorsConfig.IdleTimeoutSeconds) * time.Second,
+		}
+		options := service.NewQueryOptions(connection, user, limits)
+		t, err = service.OpenCursor(ctx, query, args, connection, options, clientID, pageSize, cursorLimits)
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			err = contextError(ctx.Err())
+		}
+		HandleError(w, err)
+		return
+	}
+	defer t.Release()
+
+	pageSize := t.Cursor.PageSize
+	if limits.MaxRows > 0 {
+		pageSize = max(min(pageSize, limits.MaxRows-t.Cursor.Returned), 0)
+	}
+
+	rows, columns, hasNext, err := service.FetchCursorPage(ctx, t, pageSize)
+	if err != nil {
+		// The transaction is aborted after an error, so the cursor can not be used anymore
+		t.Close(context.Background(), false)
+		if ctx.Err() != nil {
+			err = contextError(ctx.Err())
+		}
+		HandleError(w, err)
+		return
+	}
+
+	truncated := hasNext && limits.MaxRows > 0 && t.Cursor.Returned >= limits.MaxRows
+	if hasNext && !truncated {
+		w.Header().Set(cursorHeader, t.Token())
+	} else {
+		t.Close(context.Background(), true)
+	}
+
+	// The rows of the page are fetched, only the response size is limited
+	writeRows(w, r, rows, columns, connection, settings.LimitsConfig{MaxResponseBytes: limits.MaxResponseBytes}, format, formatOptions, nil)
+
+	if truncated && w.Header().Get(truncatedTrailer) == "" {
+		w.Header().Set(truncatedTrailer, truncatedMaxRows)
+	}
+}
+
+// getClientID returns the client id of the authenticated user of the request, empty for public connections.
+func getClientID(r *http.Request) string {
+	if user := utils.GetUserFromRequest(r); user != nil {
+		return user.ClientID
+	}
+	return ""
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/service"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -34,3 +37,39 @@ func HandleError(w http.ResponseWriter, err error) {
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// errorTrailer is the trailer set when an error is raised after the response is started.
+const errorTrailer = "X-PGRest-Error"
+
+// resultError converts an error raised while streaming a query result to an APIError.
+// Errors caused by the request context being done are converted to a timeout or canceled error.
+func resultError(ctx context.Context, err error) error {
+	if _, ok := err.(*errors.APIError); ok {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return contextError(ctx.Err())
+	}
+
+	return service.QueryError(err)
+}
+
+// handleStreamError reports an error raised after the response is started, when it is not possible
+// to change the status code anymore. The error is set as JSON in the X-PGRest-Error trailer.
+// CSV and NDJSON have no way to mark an error in the data itself, so the response is aborted without
+// terminating the chunked encoding, which clients detect as an incomplete response.
+func handleStreamError(w http.ResponseWriter, err error, format models.FormatType) {
+	log.Errorf("Error streaming query result: %v", err)
+
+	if apiErr, ok := err.(*errors.APIError); ok && apiErr.Details != nil {
+		log.Errorf("Error details: %v", *apiErr.Details)
+	}
+
+	trailer, _ := json.Marshal(err)
+	w.Header().Set(errorTrailer, string(trailer))
+
+	if format == models.CSVFormat || format == models.NDJSONFormat {
+		panic(http.ErrAbortHandler)
+	}
+}
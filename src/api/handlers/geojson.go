@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/service"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// geoJSONColumnNames are the column names recognized as GeoJSON geometry when no PostGIS geometry column is found,
+// e.g. the default column name of ST_AsGeoJSON.
+var geoJSONColumnNames = []string{"st_asgeojson", "geojson"}
+
+// geoJSONFeature represents a single row of the query result as GeoJSON feature.
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   interface{}            `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// handleFormatGeoJSON writes the query result as GeoJSON FeatureCollection to the provided writer.
+// The geometry column is the column set in the format options, or the first PostGIS geometry/geography
+// column, or the first column with ST_AsGeoJSON output. The optional id column is used as feature id,
+// all other columns are written as feature properties.
+func handleFormatGeoJSON(ctx context.Context, w http.ResponseWriter, rows pgx.Rows, connection *settings.ConnectionConfig, writer io.Writer, encoder *json.Encoder, limiter *resultLimiter, options models.FormatOptions) error {
+	geometryOIDs, err := service.GetGeometryTypeOIDs(ctx, connection)
+	if err != nil {
+		return err
+	}
+
+	fields := rows.FieldDescriptions()
+	geometryIndex, err := getGeometryColumnIndex(fields, geometryOIDs, options.GeometryColumn)
+	if err != nil {
+		return err
+	}
+
+	idIndex := -1
+	if options.IDColumn != "" {
+		if idIndex = getColumnIndex(fields, options.IDColumn); idIndex < 0 {
+			return errors.NewAPIError(http.StatusBadRequest, fmt.Sprintf("Id column '%s' not found", options.IDColumn), nil)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	writer.Write([]byte(`{"type":"FeatureCollection","features":[`))
+
+	first := true
+	for limiter.next(rows) {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		var values []interface{}
+		values, err = rows.Values()
+		if err != nil {
+			break
+		}
+
+		feature := geoJSONFeature{Type: "Feature", Properties: make(map[string]interface{}, len(fields))}
+		for i, field := range fields {
+			value := values[i]
+			if geometryOIDs[field.DataTypeOID] || i == geometryIndex {
+				if value, err = toGeoJSONGeometry(value); err != nil {
+					details := err.Error()
+					err = errors.NewAPIError(http.StatusInternalServerError, fmt.Sprintf("Error converting column '%s' to GeoJSON", field.Name), &details)
+					break
+				}
+			}
+
+			switch i {
+			case geometryIndex:
+				feature.Geometry = value
+			case idIndex:
+				feature.ID = value
+			default:
+				feature.Properties[field.Name] = value
+			}
+		}
+		if err != nil {
+			break
+		}
+
+		if !first {
+			writer.Write([]byte(`,`))
+		}
+		first = false
+
+		encoder.Encode(feature)
+	}
+
+	if err == nil {
+		err = rows.Err()
+	}
+
+	writer.Write([]byte(`]`))
+	if limiter.truncated != "" {
+		writer.Write([]byte(`,"truncated":true`))
+	}
+	if err != nil {
+		err = resultError(ctx, err)
+		writer.Write([]byte(`,"error":`))
+		encoder.Encode(err)
+	}
+	writer.Write([]byte(`}`))
+
+	return err
+}
+
+// getGeometryColumnIndex returns the index of the column to use as feature geometry.
+// It returns an APIError when the requested column is not found or no geometry column is found.
+func getGeometryColumnIndex(fields []pgconn.FieldDescription, geometryOIDs map[uint32]bool, geometryColumn string) (int, error) {
+	if geometryColumn != "" {
+		index := getColumnIndex(fields, geometryColumn)
+		if index < 0 {
+			return -1, errors.NewAPIError(http.StatusBadRequest, fmt.Sprintf("Geometry column '%s' not found", geometryColumn), nil)
+		}
+		return index, nil
+	}
+
+	for i, field := range fields {
+		if geometryOIDs[field.DataTypeOID] {
+			return i, nil
+		}
+	}
+
+	for i, field := range fields {
+		if utils.Contains(geoJSONColumnNames, strings.ToLower(field.Name)) {
+			return i, nil
+		}
+	}
+
+	details := "Select a PostGIS geometry or geography column, or set the geometryColumn"
+	return -1, errors.NewAPIError(http.StatusBadRequest, "No geometry column found", &details)
+}
+
+// getColumnIndex returns the index of the column with the given name, or -1 if not found.
+func getColumnIndex(fields []pgconn.FieldDescription, name string) int {
+	for i, field := range fields {
+		if field.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// toGeoJSONGeometry converts a geometry value to a GeoJSON geometry.
+// PostGIS geometries are decoded from (hex encoded) EWKB, JSON values are used as is
+// and text values, e.g. the output of ST_AsGeoJSON, are used as raw JSON.
+func toGeoJSONGeometry(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return utils.WKBToGeoJSON(v)
+	case string:
+		if strings.HasPrefix(v, "{") {
+			if !json.Valid([]byte(v)) {
+				return nil, fmt.Errorf("invalid GeoJSON geometry")
+			}
+			return json.RawMessage(v), nil
+		}
+		return utils.HexWKBToGeoJSON(v)
+	case map[string]interface{}:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry value %T", value)
+	}
+}
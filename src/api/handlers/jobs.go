@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/jobs"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/service"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// JobSubmitHandler handles the HTTP request for submitting a query as asynchronous job.
+// The query is executed in the background and its result is spooled to disk, the response
+// is the status of the created job. The format of the result is chosen when downloading it.
+// Jobs run on their own connection, so queries in a transaction and paged queries are rejected.
+func JobSubmitHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		connection, err := getConnection(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		body, err := getBodyData(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		if body.Transaction != "" || body.IsPaged() {
+			details := "Remove the transaction, pageSize and cursor options, jobs return the whole result"
+			HandleError(w, errors.NewAPIError(http.StatusBadRequest, "Jobs can not run in a transaction or be paged", &details))
+			return
+		}
+
+		clientID := ""
+		user := utils.GetUserFromRequest(r)
+		if user != nil {
+			clientID = user.ClientID
+		}
+		limits := settings.GetEffectiveLimits(connection, user)
+		options := service.NewQueryOptions(connection, user, limits)
+
+		query, args := body.Query, body.Args()
+		job, err := jobs.Submit(connection.Name, clientID, func(ctx context.Context, job *jobs.Job, w io.Writer) error {
+			return spoolQueryResult(ctx, job, w, connection, query, args, options, limits)
+		})
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/api/jobs/%s", job.ID))
+		writeJobInfo(w, http.StatusAccepted, job)
+	}
+}
+
+// JobStatusHandler handles the HTTP request for the status and progress of a job.
+func JobStatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := getJob(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		writeJobInfo(w, http.StatusOK, job)
+	}
+}
+
+// JobResultHandler handles the HTTP request for downloading the result of a completed job.
+// The format is set using the query string, e.g. "?format=parquet&compression=zstd", and defaults to JSON.
+// The limits are applied when the job runs, a truncated result is reported in the X-PGRest-Truncated header.
+func JobResultHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		job, err := getJob(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		format, formatOptions, err := models.ParseFormatQuery(r.URL.Query())
+		if err != nil {
+			details := err.Error()
+			HandleError(w, errors.NewAPIError(http.StatusBadRequest, "Invalid format", &details))
+			return
+		}
+
+		connection, err := config.GetConnectionConfig(job.Connection)
+		if err != nil {
+			HandleError(w, errors.NewAPIError(http.StatusBadRequest, fmt.Sprintf("Requested connection '%s' not found", job.Connection), nil))
+			return
+		}
+
+		file, err := job.OpenResult()
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		rows, err := newSpooledRows(file)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+		defer rows.Close()
+
+		if truncated := job.Info().Truncated; truncated != "" {
+			w.Header().Set(truncatedTrailer, truncated)
+		}
+
+		columns := make([]string, len(rows.FieldDescriptions()))
+		for i, field := range rows.FieldDescriptions() {
+			columns[i] = field.Name
+		}
+
+		writeRows(w, r, rows, columns, connection, settings.LimitsConfig{}, format, formatOptions, nil)
+	}
+}
+
+// JobCancelHandler handles the HTTP request for deleting a job, a queued or running job is canceled
+// and the query is canceled on the database. The spooled result is removed.
+func JobCancelHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.GetJobIDFromRequest(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		if !jobs.Delete(id) {
+			HandleError(w, errors.NewAPIError(http.StatusNotFound, "Job not found", nil))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getJob retrieves the job requested in the URL.
+// It returns an APIError if the job id is missing or the job is not found, e.g. because it expired.
+func getJob(r *http.Request) (*jobs.Job, error) {
+	id, err := utils.GetJobIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	job, ok := jobs.Get(id)
+	if !ok {
+		return nil, errors.NewAPIError(http.StatusNotFound, "Job not found", nil)
+	}
+
+	return job, nil
+}
+
+// writeJobInfo writes the status and progress of the job as JSON response with the given status code.
+func writeJobInfo(w http.ResponseWriter, statusCode int, job *jobs.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(job.Info())
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+const (
+	truncatedTrailer = "X-PGRest-Truncated" // Trailer set to the reason when the result is truncated by a limit
+
+	truncatedMaxRows          = "maxRows"
+	truncatedMaxResponseBytes = "maxResponseBytes"
+)
+
+// countingWriter wraps a writer and counts the number of bytes written to it.
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// resultLimiter enforces the maximum number of rows and response bytes while streaming a query result.
+// The limits are checked between rows, so the response is always ended in a well-defined way per format.
+type resultLimiter struct {
+	maxRows   int
+	maxBytes  int64
+	counter   *countingWriter
+	rowCount  int
+	truncated string // The reason the result was truncated, empty if not truncated
+}
+
+// newResultLimiter creates a resultLimiter for the limits counting the response bytes using the counter.
+func newResultLimiter(limits settings.LimitsConfig, counter *countingWriter) *resultLimiter {
+	return &resultLimiter{
+		maxRows:  limits.MaxRows,
+		maxBytes: limits.MaxResponseBytes,
+		counter:  counter,
+	}
+}
+
+// next advances the rows like rows.Next, but returns false when a limit is reached.
+// When more rows are available after reaching a limit the result is marked as truncated.
+func (l *resultLimiter) next(rows pgx.Rows) bool {
+	reason := ""
+	if l.maxRows > 0 && l.rowCount >= l.maxRows {
+		reason = truncatedMaxRows
+	} else if l.maxBytes > 0 && l.counter != nil && l.counter.written >= l.maxBytes {
+		reason = truncatedMaxResponseBytes
+	}
+
+	if reason != "" {
+		if rows.Next() {
+			l.truncated = reason
+		}
+		return false
+	}
+
+	if !rows.Next() {
+		return false
+	}
+
+	l.rowCount++
+	return true
+}
+
+// setLimitHeaders reports the effective limits in the response headers and announces the truncated trailer.
+func setLimitHeaders(w http.ResponseWriter, limits settings.LimitsConfig) {
+	if limits.StatementTimeoutMs > 0 {
+		w.Header().Set("X-PGRest-Statement-Timeout", strconv.Itoa(limits.StatementTimeoutMs))
+	}
+
+	if limits.MaxRows > 0 {
+		w.Header().Set("X-PGRest-Max-Rows", strconv.Itoa(limits.MaxRows))
+	}
+
+	if limits.MaxResponseBytes > 0 {
+		w.Header().Set("X-PGRest-Max-Response-Bytes", strconv.FormatInt(limits.MaxResponseBytes, 10))
+	}
+
+	w.Header().Add("Trailer", truncatedTrailer)
+}
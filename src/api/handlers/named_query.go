@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// NamedQueryHandler handles the HTTP request for executing a named query from the query catalog of a connection.
+// Clients only provide the params and format, the query itself and its param types are defined in the configuration.
+func NamedQueryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		connection, err := getConnection(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		queryName, err := utils.GetQueryNameFromRequest(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		namedQuery, err := connection.GetNamedQuery(queryName)
+		if err != nil {
+			apiError := errors.NewAPIError(http.StatusNotFound, fmt.Sprintf("Requested query '%s' not found", queryName), nil)
+			HandleError(w, apiError)
+			return
+		}
+
+		body, err := getNamedQueryBodyData(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		args, err := models.ValidateParams(body.Params, namedQuery.ParamTypes)
+		if err != nil {
+			details := err.Error()
+			HandleError(w, errors.NewAPIError(http.StatusBadRequest, "Invalid query params", &details))
+			return
+		}
+
+		if body.Transaction != "" {
+			writeTransactionQueryResult(w, r, connection, body.Transaction, namedQuery.Query, args, body.Format, body.FormatOptions)
+			return
+		}
+
+		if body.IsPaged() {
+			writeQueryPage(w, r, config, connection, namedQuery.Query, args, body.Format, body.FormatOptions, body.PagingOptions)
+			return
+		}
+
+		writeQueryResult(w, r, connection, namedQuery.Query, args, body.Format, body.FormatOptions, namedQuery.GetCacheTTLSeconds(connection))
+	}
+}
+
+// getNamedQueryBodyData reads the request body from the provided http.Request and returns
+// the decoded JSON payload as a NamedQueryRequestBody struct.
+// An empty body is allowed for named queries without params.
+func getNamedQueryBodyData(r *http.Request) (*models.NamedQueryRequestBody, error) {
+	var requestBody models.NamedQueryRequestBody
+
+	body := utils.GetBodyString(r)
+	if body == "" {
+		body = "{}"
+	}
+
+	if err := json.Unmarshal([]byte(body), &requestBody); err != nil {
+		details := err.Error()
+		return nil, errors.NewAPIError(http.StatusBadRequest, "Invalid request body", &details)
+	}
+
+	return &requestBody, nil
+}
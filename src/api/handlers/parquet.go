@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/parquet"
+	"github.com/apache/arrow/go/v18/parquet/compress"
+	"github.com/apache/arrow/go/v18/parquet/pqarrow"
+	"github.com/jackc/pgx/v5"
+	"github.com/pierrec/lz4/v4"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/models"
+)
+
+// lz4RawCompression is the LZ4_RAW Parquet codec, the LZ4 block format without framing.
+// The Arrow Parquet package does not provide an LZ4 codec, so it is registered here.
+const lz4RawCompression = compress.Compression(7)
+
+var parquetCompressions = map[models.ParquetCompression]compress.Compression{
+	models.ParquetSnappy:       compress.Codecs.Snappy,
+	models.ParquetZstd:         compress.Codecs.Zstd,
+	models.ParquetGzip:         compress.Codecs.Gzip,
+	models.ParquetLz4:          lz4RawCompression,
+	models.ParquetUncompressed: compress.Codecs.Uncompressed,
+}
+
+func init() {
+	compress.RegisterCodec(lz4RawCompression, lz4RawCodec{})
+}
+
+// handleFormatParquet writes the given rows to the provided writer in Parquet format.
+// Each row group is written to the response as soon as it is complete, so only a single row group
+// is kept in memory. When an error is raised while streaming the file footer is not written,
+// so readers detect the file is incomplete.
+func handleFormatParquet(ctx context.Context, w http.ResponseWriter, rows pgx.Rows, writer io.Writer, limiter *resultLimiter, options models.FormatOptions) error {
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	schema := createArrowSchema(rows.FieldDescriptions(), true)
+
+	writerProps := parquet.NewWriterProperties(
+		parquet.WithCompression(parquetCompressions[options.Compression]),
+		parquet.WithMaxRowGroupLength(int64(options.RowGroupSize)),
+	)
+	pw, err := pqarrow.NewFileWriter(schema, writer, writerProps, pqarrow.DefaultWriterProps())
+	if err != nil {
+		details := err.Error()
+		return errors.NewAPIError(http.StatusInternalServerError, "Error creating Parquet writer", &details)
+	}
+
+	recordBuilder := createRecordBuilder(schema)
+	defer recordBuilder.Release()
+
+	// Every record is written as a single row group
+	err = writeArrowRecords(ctx, rows, recordBuilder, options.RowGroupSize, limiter, func(record arrow.Record) error {
+		if err := pw.Write(record); err != nil {
+			details := err.Error()
+			return errors.NewAPIError(http.StatusInternalServerError, "Error writing Parquet row group", &details)
+		}
+		return nil
+	})
+	if err != nil {
+		return resultError(ctx, err)
+	}
+
+	if err := pw.Close(); err != nil {
+		details := err.Error()
+		return errors.NewAPIError(http.StatusInternalServerError, "Error closing Parquet writer", &details)
+	}
+
+	return nil
+}
+
+// lz4RawCodec implements the LZ4_RAW Parquet codec using the LZ4 block format.
+type lz4RawCodec struct{}
+
+func (lz4RawCodec) Encode(dst, src []byte) []byte {
+	bound := lz4.CompressBlockBound(len(src))
+	if cap(dst) < bound {
+		dst = make([]byte, bound)
+	}
+	dst = dst[:bound]
+
+	n, err := lz4.CompressBlock(src, dst, nil)
+	if err != nil {
+		panic(err)
+	}
+	return dst[:n]
+}
+
+func (c lz4RawCodec) EncodeLevel(dst, src []byte, _ int) []byte {
+	return c.Encode(dst, src)
+}
+
+func (lz4RawCodec) Decode(dst, src []byte) []byte {
+	n, err := lz4.UncompressBlock(src, dst)
+	if err != nil {
+		panic(err)
+	}
+	return dst[:n]
+}
+
+func (lz4RawCodec) CompressBound(len int64) int64 {
+	return int64(lz4.CompressBlockBound(int(len)))
+}
+
+// NewReader is not used for Parquet pages, which are always compressed as a single block.
+func (lz4RawCodec) NewReader(r io.Reader) io.ReadCloser {
+	return io.NopCloser(errorReader{fmt.Errorf("lz4 raw codec does not support streaming")})
+}
+
+// NewWriter compresses all data written as a single block when closed.
+func (lz4RawCodec) NewWriter(w io.Writer) io.WriteCloser {
+	return &lz4RawWriter{writer: w}
+}
+
+func (c lz4RawCodec) NewWriterLevel(w io.Writer, _ int) (io.WriteCloser, error) {
+	return c.NewWriter(w), nil
+}
+
+type lz4RawWriter struct {
+	writer io.Writer
+	data   []byte
+}
+
+func (l *lz4RawWriter) Write(p []byte) (int, error) {
+	l.data = append(l.data, p...)
+	return len(p), nil
+}
+
+func (l *lz4RawWriter) Close() error {
+	_, err := l.writer.Write(lz4RawCodec{}.Encode(nil, l.data))
+	return err
+}
+
+type errorReader struct {
+	err error
+}
+
+func (e errorReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestLz4RawCodec(t *testing.T) {
+	random := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: []byte{}},
+		{name: "single byte", data: []byte{1}},
+		{name: "compressible", data: bytes.Repeat([]byte("pgrest "), 10000)},
+		{name: "incompressible", data: random},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			codec := lz4RawCodec{}
+			compressed := codec.Encode(nil, test.data)
+			if int64(len(compressed)) > codec.CompressBound(int64(len(test.data))) {
+				t.Errorf("compressed %d bytes to %d bytes, more than the bound %d", len(test.data), len(compressed), codec.CompressBound(int64(len(test.data))))
+			}
+
+			// Parquet decodes into a buffer of the uncompressed page size
+			decoded := codec.Decode(make([]byte, len(test.data)), compressed)
+			if !bytes.Equal(decoded, test.data) {
+				t.Errorf("decoded %d bytes, want the %d original bytes", len(decoded), len(test.data))
+			}
+
+			var buffer bytes.Buffer
+			writer := codec.NewWriter(&buffer)
+			writer.Write(test.data)
+			if err := writer.Close(); err != nil {
+				t.Fatalf("closing the writer: %v", err)
+			}
+			if !bytes.Equal(buffer.Bytes(), compressed) {
+				t.Errorf("writer wrote %d bytes, want the %d bytes of Encode", buffer.Len(), len(compressed))
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/csv"
@@ -16,6 +15,7 @@ import (
 
 	"github.com/andybalholm/brotli"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/sogelink-research/pgrest/errors"
 	"github.com/sogelink-research/pgrest/models"
 	"github.com/sogelink-research/pgrest/service"
@@ -27,105 +27,224 @@ import (
 	"github.com/apache/arrow/go/v18/arrow/endian"
 	"github.com/apache/arrow/go/v18/arrow/ipc"
 	"github.com/apache/arrow/go/v18/arrow/memory"
-	"github.com/apache/arrow/go/v18/parquet"
-	"github.com/apache/arrow/go/v18/parquet/compress"
-	"github.com/apache/arrow/go/v18/parquet/pqarrow"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // QueryHandler handles the HTTP request for executing a database query.
 // It takes in the HTTP response writer, the HTTP request and the database connection configuration.
 // It returns an error if there was an issue connecting to the database or executing the query.
-func QueryHandler(config settings.Config) http.HandlerFunc {
+func QueryHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		doneChan := make(chan struct{})
-		go func() {
-			defer close(doneChan)
+		config := settings.GetConfig()
 
-			connectionName, err := utils.GetConnectionNameFromRequest(r)
-			if err != nil {
-				HandleError(w, err)
-				return
-			}
+		connection, err := getConnection(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
 
-			// Find the requested database connection
-			connection, err := config.GetConnectionConfig(connectionName)
-			if err != nil {
-				apiError := errors.NewAPIError(http.StatusBadRequest, fmt.Sprintf("Requested connection '%s' not found", connectionName), nil)
-				HandleError(w, apiError)
-				return
-			}
+		body, err := getBodyData(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
 
-			body, err := getBodyData(r)
-			if err != nil {
-				HandleError(w, err)
+		if body.Transaction != "" {
+			if connection.RowLevelSecurity {
+				HandleError(w, adHocSQLError(connection, "in a transaction"))
 				return
 			}
+			writeTransactionQueryResult(w, r, connection, body.Transaction, body.Query, body.Args(), body.Format, body.FormatOptions)
+			return
+		}
 
-			rows, columns, err := service.QueryPostgres(body.Query, connection)
-			if err != nil {
-				HandleError(w, err)
+		if body.IsPaged() {
+			if connection.RowLevelSecurity {
+				// The cursor is a transaction held open across requests
+				HandleError(w, adHocSQLError(connection, "in a paged query"))
 				return
 			}
+			writeQueryPage(w, r, config, connection, body.Query, body.Args(), body.Format, body.FormatOptions, body.PagingOptions)
+			return
+		}
 
-			defer rows.Close()
+		writeQueryResult(w, r, connection, body.Query, body.Args(), body.Format, body.FormatOptions, connection.Cache.TTLSeconds)
+	}
+}
 
-			var encoder *json.Encoder
-			var writer io.Writer
+// contextError returns the APIError for a request of which the context is done.
+// A canceled context means the client disconnected, an exceeded deadline means the request timed out.
+func contextError(err error) error {
+	if err == context.DeadlineExceeded {
+		return errors.NewAPIError(http.StatusGatewayTimeout, "Processing too slow", nil)
+	}
+	return errors.NewAPIError(http.StatusRequestTimeout, "Request canceled", nil)
+}
 
-			const bufferSize = 64 * 1024 // 64 KB
+// logContextDone logs why the query on the connection was canceled when the request context is done.
+func logContextDone(ctx context.Context, connection *settings.ConnectionConfig) {
+	switch ctx.Err() {
+	case context.Canceled:
+		log.Warnf("Query on connection '%s' canceled: client disconnected", connection.Name)
+	case context.DeadlineExceeded:
+		log.Warnf("Query on connection '%s' canceled: request timed out", connection.Name)
+	}
+}
 
-			bw := bufio.NewWriterSize(w, bufferSize)
-			defer bw.Flush()
+// getConnection retrieves the configuration of the connection requested in the URL.
+// It returns an APIError if the connection name is missing or the connection is not found.
+func getConnection(r *http.Request, config settings.Config) (*settings.ConnectionConfig, error) {
+	connectionName, err := utils.GetConnectionNameFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
 
-			acceptEncoding := r.Header.Get("Accept-Encoding")
-			if strings.Contains(acceptEncoding, "br") {
-				w.Header().Set("Content-Encoding", "br")
-				brWriter := brotli.NewWriterLevel(bw, brotli.DefaultCompression)
-				defer brWriter.Close()
-				writer = brWriter
-			} else if strings.Contains(acceptEncoding, "gzip") {
-				w.Header().Set("Content-Encoding", "gzip")
-				var gz *gzip.Writer
-				gz, _ = gzip.NewWriterLevel(bw, gzip.DefaultCompression)
-				defer gz.Close()
-				writer = gz
-			} else {
-				writer = bw
-			}
+	// Find the requested database connection
+	connection, err := config.GetConnectionConfig(connectionName)
+	if err != nil {
+		return nil, errors.NewAPIError(http.StatusBadRequest, fmt.Sprintf("Requested connection '%s' not found", connectionName), nil)
+	}
 
-			encoder = json.NewEncoder(writer)
-
-			switch body.Format {
-			case models.JSONFormat:
-				handleFormatJSON(w, rows, columns, writer, encoder)
-			case models.JSONDataArrayFormat:
-				handleFormatJSONDataArray(w, rows, columns, writer, encoder)
-			case models.ArrowFormat:
-				handleFormatArrow(w, rows, writer, 1000)
-			case models.CSVFormat:
-				handleFormatCSV(w, rows, columns, writer)
-			case models.ParquetFormat:
-				handleFormatParquet(w, rows, 1000, writer)
-			default:
-				handleFormatJSON(w, rows, columns, writer, encoder)
-			}
-		}()
-
-		select {
-		case <-r.Context().Done():
-			err := r.Context().Err()
-			if err == context.Canceled {
-				err = errors.NewAPIError(http.StatusRequestTimeout, "Request canceled", nil)
-				HandleError(w, err)
-				return
-			} else if err == context.DeadlineExceeded {
-				err = errors.NewAPIError(http.StatusGatewayTimeout, "Processing too slow", nil)
-				HandleError(w, err)
-				return
-			}
-			return
-		case <-doneChan:
+	return connection, nil
+}
+
+// adHocSQLError returns the APIError for ad-hoc SQL in a batch, transaction or paged query on a connection with row-level security.
+// The session settings of the user are set once per transaction, ad-hoc SQL executed later in the same
+// transaction could change them with set_config or SET ROLE, so only named queries are allowed.
+func adHocSQLError(connection *settings.ConnectionConfig, where string) error {
+	details := "Use named queries on this connection"
+	return errors.NewAPIError(http.StatusForbidden, fmt.Sprintf("Ad-hoc SQL is not allowed %s on connection '%s' with row-level security", where, connection.Name), &details)
+}
+
+// writeQueryResult executes the query with the given args on the connection and streams
+// the result to the client in the requested format, compressed based on the Accept-Encoding header.
+// The query is canceled when the request context is done, e.g. when the client disconnects or the request times out.
+// When cacheTTLSeconds is set the result is served from and stored in the result cache.
+// Errors raised after the response is started are reported in the X-PGRest-Error trailer.
+func writeQueryResult(w http.ResponseWriter, r *http.Request, connection *settings.ConnectionConfig, query string, args []interface{}, format models.FormatType, formatOptions models.FormatOptions, cacheTTLSeconds int) {
+	ctx := r.Context()
+	defer logContextDone(ctx, connection)
+
+	user := utils.GetUserFromRequest(r)
+	limits := settings.GetEffectiveLimits(connection, user)
+	setLimitHeaders(w, limits)
+
+	resultCache, handled := getResultCache(w, r, cacheTTLSeconds, connection, query, args, format, formatOptions, limits)
+	if handled {
+		return
+	}
+
+	options := service.NewQueryOptions(connection, user, limits)
+	rows, columns, err := service.QueryPostgres(ctx, query, args, connection, options)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = contextError(ctx.Err())
 		}
+		HandleError(w, err)
+		return
+	}
+
+	defer rows.Close()
+
+	writeRows(w, r, rows, columns, connection, limits, format, formatOptions, resultCache)
+}
+
+// writeRows streams the rows to the client in the requested format, compressed based on the Accept-Encoding header,
+// enforcing the limits. It is used for query results and the spooled results of jobs.
+// When resultCache is set the uncompressed result is stored in the result cache when it is written without error.
+// Errors raised after the response is started are reported in the X-PGRest-Error trailer.
+func writeRows(w http.ResponseWriter, r *http.Request, rows pgx.Rows, columns []string, connection *settings.ConnectionConfig, limits settings.LimitsConfig, format models.FormatType, formatOptions models.FormatOptions, resultCache *resultCache) {
+	ctx := r.Context()
+
+	const bufferSize = 64 * 1024 // 64 KB
+
+	bw := bufio.NewWriterSize(w, bufferSize)
+	compressionWriter, closeWriter := newCompressionWriter(w, r, bw)
+
+	// Count the uncompressed response bytes to enforce the response size limit
+	counter := &countingWriter{writer: compressionWriter}
+	var writer io.Writer = counter
+	if resultCache != nil {
+		writer = io.MultiWriter(counter, resultCache)
+	}
+	limiter := newResultLimiter(limits, counter)
+
+	encoder := json.NewEncoder(writer)
+
+	w.Header().Add("Trailer", errorTrailer)
+
+	var err error
+	switch format {
+	case models.JSONFormat:
+		err = handleFormatJSON(ctx, w, rows, columns, writer, encoder, limiter)
+	case models.JSONDataArrayFormat:
+		err = handleFormatJSONDataArray(ctx, w, rows, columns, writer, encoder, limiter)
+	case models.ArrowFormat:
+		err = handleFormatArrow(ctx, w, rows, writer, 1000, limiter)
+	case models.NDJSONFormat:
+		err = handleFormatNDJSON(ctx, w, rows, columns, encoder, limiter, func() { flushResponse(w, bw, compressionWriter) })
+	case models.GeoJSONFormat:
+		err = handleFormatGeoJSON(ctx, w, rows, connection, writer, encoder, limiter, formatOptions)
+	case models.CSVFormat:
+		err = handleFormatCSV(ctx, w, rows, columns, writer, limiter)
+	case models.ParquetFormat:
+		err = handleFormatParquet(ctx, w, rows, writer, limiter, formatOptions)
+	default:
+		err = handleFormatJSON(ctx, w, rows, columns, writer, encoder, limiter)
+	}
+
+	// Nothing is written yet, so the error can still be returned as error response
+	if err != nil && counter.written == 0 {
+		w.Header().Del("Content-Encoding")
+		HandleError(w, err)
+		return
+	}
+
+	closeWriter()
+	bw.Flush()
+
+	if limiter.truncated != "" {
+		w.Header().Set(truncatedTrailer, limiter.truncated)
+	}
+
+	if err != nil {
+		handleStreamError(w, err, format)
+		return
+	}
+
+	if resultCache != nil {
+		resultCache.store(w, limiter.truncated)
+	}
+}
+
+// newCompressionWriter returns the writer compressing the response based on the Accept-Encoding header,
+// writing to the buffered writer, and the function to close it.
+func newCompressionWriter(w http.ResponseWriter, r *http.Request, bw *bufio.Writer) (io.Writer, func()) {
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+	if strings.Contains(acceptEncoding, "br") {
+		w.Header().Set("Content-Encoding", "br")
+		brWriter := brotli.NewWriterLevel(bw, brotli.DefaultCompression)
+		return brWriter, func() { brWriter.Close() }
+	} else if strings.Contains(acceptEncoding, "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz, _ := gzip.NewWriterLevel(bw, gzip.DefaultCompression)
+		return gz, func() { gz.Close() }
+	}
+
+	return bw, func() {}
+}
+
+// flushResponse flushes the data written to the compression writer and buffered writer to the client.
+func flushResponse(w http.ResponseWriter, bw *bufio.Writer, compressionWriter io.Writer) {
+	if flusher, ok := compressionWriter.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+
+	bw.Flush()
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
 	}
 }
 
@@ -134,15 +253,24 @@ func QueryHandler(config settings.Config) http.HandlerFunc {
 // and the encoder to encode the JSON data.
 // The function iterates over the rows, converts them into a map with column names as keys,
 // and writes the JSON-encoded rows to the writer.
-// The resulting JSON is wrapped in a data array.
-func handleFormatJSON(w http.ResponseWriter, rows pgx.Rows, columns []string, writer io.Writer, encoder *json.Encoder) error {
+// The resulting JSON is wrapped in a data array, an error raised while streaming is added as error field.
+func handleFormatJSON(ctx context.Context, w http.ResponseWriter, rows pgx.Rows, columns []string, writer io.Writer, encoder *json.Encoder, limiter *resultLimiter) error {
 	w.Header().Set("Content-Type", "application/json")
 	jsonStart := []byte(`{"data":[`)
 	writer.Write(jsonStart)
 
+	var err error
 	first := true
-	for rows.Next() {
-		values, _ := rows.Values()
+	for limiter.next(rows) {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		var values []interface{}
+		values, err = rows.Values()
+		if err != nil {
+			break
+		}
 
 		row := make(map[string]interface{})
 		for i, col := range columns {
@@ -157,13 +285,27 @@ func handleFormatJSON(w http.ResponseWriter, rows pgx.Rows, columns []string, wr
 		encoder.Encode(row)
 	}
 
-	writer.Write([]byte(`]}`))
-	return nil
+	if err == nil {
+		err = rows.Err()
+	}
+
+	writer.Write([]byte(`]`))
+	if limiter.truncated != "" {
+		writer.Write([]byte(`,"truncated":true`))
+	}
+	if err != nil {
+		err = resultError(ctx, err)
+		writer.Write([]byte(`,"error":`))
+		encoder.Encode(err)
+	}
+	writer.Write([]byte(`}`))
+
+	return err
 }
 
 // handleFormatJSONDataArray formats the data from the given rows and writes it to the writer in JSON format.
-// It includes the fields and rows information in the JSON output.
-func handleFormatJSONDataArray(w http.ResponseWriter, rows pgx.Rows, columns []string, writer io.Writer, encoder *json.Encoder) {
+// It includes the fields and rows information in the JSON output, an error raised while streaming is added as error field.
+func handleFormatJSONDataArray(ctx context.Context, w http.ResponseWriter, rows pgx.Rows, columns []string, writer io.Writer, encoder *json.Encoder, limiter *resultLimiter) error {
 	w.Header().Set("Content-Type", "application/json")
 	jsonStart := []byte(`{"data": {`)
 	writer.Write(jsonStart)
@@ -174,9 +316,18 @@ func handleFormatJSONDataArray(w http.ResponseWriter, rows pgx.Rows, columns []s
 	jsonRows := []byte(`"rows":[`)
 	writer.Write(jsonRows)
 
+	var err error
 	first := true
-	for rows.Next() {
-		values, _ := rows.Values()
+	for limiter.next(rows) {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		var values []interface{}
+		values, err = rows.Values()
+		if err != nil {
+			break
+		}
 
 		if !first {
 			writer.Write([]byte(`,`))
@@ -186,28 +337,84 @@ func handleFormatJSONDataArray(w http.ResponseWriter, rows pgx.Rows, columns []s
 		encoder.Encode(values)
 	}
 
-	writer.Write([]byte(`]}}`))
+	if err == nil {
+		err = rows.Err()
+	}
+
+	writer.Write([]byte(`]}`))
+	if limiter.truncated != "" {
+		writer.Write([]byte(`,"truncated":true`))
+	}
+	if err != nil {
+		err = resultError(ctx, err)
+		writer.Write([]byte(`,"error":`))
+		encoder.Encode(err)
+	}
+	writer.Write([]byte(`}`))
+
+	return err
+}
+
+// handleFormatNDJSON writes the query result as newline-delimited JSON, one JSON object per row.
+// The response is flushed periodically so consumers can process the rows as they arrive.
+func handleFormatNDJSON(ctx context.Context, w http.ResponseWriter, rows pgx.Rows, columns []string, encoder *json.Encoder, limiter *resultLimiter, flush func()) error {
+	const flushInterval = time.Second
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	lastFlush := time.Now()
+	for limiter.next(rows) {
+		if err := ctx.Err(); err != nil {
+			return resultError(ctx, err)
+		}
+
+		values, err := rows.Values()
+		if err != nil {
+			return resultError(ctx, err)
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			row[col] = values[i]
+		}
+
+		encoder.Encode(row)
+
+		if time.Since(lastFlush) >= flushInterval {
+			flush()
+			lastFlush = time.Now()
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return resultError(ctx, err)
+	}
+
+	return nil
 }
 
 // handleFormatCSV writes the given rows and columns to the provided writer in CSV format.
-// It sets the appropriate Content-Type header and handles any errors that occur during the process.
-func handleFormatCSV(w http.ResponseWriter, rows pgx.Rows, columns []string, writer io.Writer) {
+// It sets the appropriate Content-Type header and returns the error raised while streaming, if any.
+func handleFormatCSV(ctx context.Context, w http.ResponseWriter, rows pgx.Rows, columns []string, writer io.Writer, limiter *resultLimiter) error {
 	w.Header().Set("Content-Type", "text/csv")
 	csvWriter := csv.NewWriter(writer)
 
 	// Write the CSV header
 	if err := csvWriter.Write(columns); err != nil {
-		apiError := errors.NewAPIError(http.StatusInternalServerError, "Error writing CSV header", nil)
-		HandleError(w, apiError)
-		return
+		details := err.Error()
+		return errors.NewAPIError(http.StatusInternalServerError, "Error writing CSV header", &details)
 	}
 
-	for rows.Next() {
-		values, err := rows.Values()
+	var err error
+	for limiter.next(rows) {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		var values []interface{}
+		values, err = rows.Values()
 		if err != nil {
-			apiError := errors.NewAPIError(http.StatusInternalServerError, "Error reading row values", nil)
-			HandleError(w, apiError)
-			return
+			break
 		}
 
 		strValues := make([]string, len(values))
@@ -219,35 +426,44 @@ func handleFormatCSV(w http.ResponseWriter, rows pgx.Rows, columns []string, wri
 			}
 		}
 
-		if err := csvWriter.Write(strValues); err != nil {
-			apiError := errors.NewAPIError(http.StatusInternalServerError, "Error writing CSV row", nil)
-			HandleError(w, apiError)
-			return
+		if err = csvWriter.Write(strValues); err != nil {
+			details := err.Error()
+			err = errors.NewAPIError(http.StatusInternalServerError, "Error writing CSV row", &details)
+			break
 		}
 	}
 
+	if err == nil {
+		err = rows.Err()
+	}
+
 	// Flush the CSV writer to ensure all data is written
 	csvWriter.Flush()
+	if err != nil {
+		return resultError(ctx, err)
+	}
+
 	if err := csvWriter.Error(); err != nil {
-		apiError := errors.NewAPIError(http.StatusInternalServerError, "Error flushing CSV writer", nil)
-		HandleError(w, apiError)
-		return
+		details := err.Error()
+		return errors.NewAPIError(http.StatusInternalServerError, "Error flushing CSV writer", &details)
 	}
+
+	return nil
 }
 
 // createArrowSchema creates Arrow schema from column descriptions
-func createArrowSchema(rows pgx.Rows) (*arrow.Schema, error) {
-	columns := rows.FieldDescriptions()
+// When forParquet is true, types which can not be written to Parquet are replaced.
+func createArrowSchema(columns []pgconn.FieldDescription, forParquet bool) *arrow.Schema {
 	fields := make([]arrow.Field, len(columns))
 	for i, col := range columns {
-		arrowType, err := utils.PGTypeToArrowType(col.DataTypeOID)
-		if err != nil {
-			return nil, fmt.Errorf("unsupported column type %d for column %s: %v", col.DataTypeOID, col.Name, err)
+		arrowType := utils.PGTypeToArrowType(col.DataTypeOID, col.TypeModifier)
+		if forParquet {
+			arrowType = utils.ToParquetCompatibleType(arrowType)
 		}
 		fields[i] = arrow.Field{Name: col.Name, Type: arrowType, Nullable: true}
 	}
 
-	return arrow.NewSchemaWithEndian(fields, nil, endian.NativeEndian), nil
+	return arrow.NewSchemaWithEndian(fields, nil, endian.NativeEndian)
 }
 
 // Create RecordBuilder for a given schema
@@ -256,219 +472,85 @@ func createRecordBuilder(schema *arrow.Schema) *array.RecordBuilder {
 	return array.NewRecordBuilder(pool, schema)
 }
 
-// Append values to the appropriate RecordBuilder field
-func appendArrowValues(recordBuilder *array.RecordBuilder, values []interface{}) error {
-	for i, value := range values {
-		switch builder := recordBuilder.Field(i).(type) {
-		case *array.Int64Builder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				builder.Append(value.(int64))
-			}
-		case *array.Int32Builder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				builder.Append(value.(int32))
-			}
-		case *array.Int16Builder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				builder.Append(value.(int16))
-			}
-		case *array.Float32Builder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				builder.Append(value.(float32))
-			}
-		case *array.Float64Builder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				builder.Append(value.(float64))
-			}
-		case *array.BooleanBuilder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				builder.Append(value.(bool))
-			}
-		case *array.StringBuilder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				builder.Append(value.(string))
-			}
-		case *array.TimestampBuilder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				timestamp := value.(time.Time)
-				millis := timestamp.UnixNano() / int64(time.Millisecond)
-				builder.Append(arrow.Timestamp(millis))
-			}
-		case *array.BinaryBuilder:
-			if value == nil {
-				builder.AppendNull()
-			} else {
-				builder.Append(value.([]byte))
-			}
-		default:
-			return fmt.Errorf("unsupported builder type %T", builder)
-		}
-	}
-	return nil
-}
-
-// handleFormatParquet writes the given rows to the provided writer in Parquet format.
-func handleFormatParquet(w http.ResponseWriter, rows pgx.Rows, batchSize int, writer io.Writer) {
-	w.Header().Set("Content-Type", "application/octet-stream")
-
-	schema, err := createArrowSchema(rows)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+// handleFormatArrow handles the formatting of the query results in Apache Arrow format.
+// When an error is raised while streaming the end-of-stream marker is not written,
+// so readers detect the stream is incomplete.
+func handleFormatArrow(ctx context.Context, w http.ResponseWriter, rows pgx.Rows, writer io.Writer, batchSize int, limiter *resultLimiter) error {
+	w.Header().Set("Content-Type", "application/vnd.apache.arrow.stream")
 
-	buf := new(bytes.Buffer)
+	schema := createArrowSchema(rows.FieldDescriptions(), false)
 
-	writerProps := parquet.NewWriterProperties(parquet.WithBatchSize(int64(batchSize)), parquet.WithCompression(compress.Codecs.Snappy))
-	pw, err := pqarrow.NewFileWriter(schema, buf, writerProps, pqarrow.DefaultWriterProps())
-	if err != nil {
-		details := err.Error()
-		err = errors.NewAPIError(http.StatusInternalServerError, "Error creating Parquet writer", &details)
-		HandleError(w, err)
-		return
-	}
+	arrWriter := ipc.NewWriter(writer, ipc.WithSchema(schema))
 
 	recordBuilder := createRecordBuilder(schema)
 	defer recordBuilder.Release()
 
-	// Iterate over rows
-	var recordCounter int
-	for rows.Next() {
-		values, err := rows.Values()
-		if err != nil {
-			detail := err.Error()
-			err := errors.NewAPIError(http.StatusInternalServerError, "Error reading row values", &detail)
-			HandleError(w, err)
-			return
-		}
-
-		if err := appendArrowValues(recordBuilder, values); err != nil {
-			details := err.Error()
-			err := errors.NewAPIError(http.StatusInternalServerError, "Error appending arrow value", &details)
-			HandleError(w, err)
-			return
-		}
-
-		recordCounter++
-
-		if recordCounter >= batchSize {
-			record := recordBuilder.NewRecord()
-			if err := pw.Write(record); err != nil {
-				details := err.Error()
-				err := errors.NewAPIError(http.StatusInternalServerError, "Error writing Parquet record", &details)
-				HandleError(w, err)
-				return
-			}
-			recordCounter = 0
-			record.Release()
-		}
-	}
-
-	if recordCounter > 0 {
-		record := recordBuilder.NewRecord()
-		if err := pw.Write(record); err != nil {
+	err := writeArrowRecords(ctx, rows, recordBuilder, batchSize, limiter, func(record arrow.Record) error {
+		if err := arrWriter.Write(record); err != nil {
 			details := err.Error()
-			err := errors.NewAPIError(http.StatusInternalServerError, "Error writing Parquet record", &details)
-			HandleError(w, err)
-			return
+			return errors.NewAPIError(http.StatusInternalServerError, "Error writing record batch", &details)
 		}
-
-		record.Release()
-	}
-
-	err = pw.Close()
+		return nil
+	})
 	if err != nil {
-		err := errors.NewAPIError(http.StatusInternalServerError, "Error closing Parquet writer", nil)
-		HandleError(w, err)
-		return
+		return resultError(ctx, err)
 	}
 
-	_, err = writer.Write(buf.Bytes())
-	if err != nil {
-		err := errors.NewAPIError(http.StatusInternalServerError, "Error writing Parquet data", nil)
-		HandleError(w, err)
-		return
+	if err := arrWriter.Close(); err != nil {
+		details := err.Error()
+		return errors.NewAPIError(http.StatusInternalServerError, "Error closing Arrow writer", &details)
 	}
-}
 
-// handleFormatArrow handles the formatting of the query results in Apache Arrow format.
-func handleFormatArrow(w http.ResponseWriter, rows pgx.Rows, writer io.Writer, batchSize int) {
-	w.Header().Set("Content-Type", "application/vnd.apache.arrow.stream")
+	return nil
+}
 
-	schema, err := createArrowSchema(rows)
-	if err != nil {
-		details := err.Error()
-		apiErr := errors.NewAPIError(http.StatusInternalServerError, "Error creating Arrow schema", &details)
-		HandleError(w, apiErr)
-		return
+// writeArrowRecords appends the rows to the record builder and calls write for each record
+// of batchSize rows and for the remaining rows.
+// It returns the first error raised reading the rows, appending the values or writing a record.
+func writeArrowRecords(ctx context.Context, rows pgx.Rows, recordBuilder *array.RecordBuilder, batchSize int, limiter *resultLimiter, write func(record arrow.Record) error) error {
+	writeRecord := func() error {
+		record := recordBuilder.NewRecord()
+		defer record.Release()
+		return write(record)
 	}
 
-	arrWriter := ipc.NewWriter(writer, ipc.WithSchema(schema))
-	defer arrWriter.Close()
-
-	recordBuilder := createRecordBuilder(schema)
-	defer recordBuilder.Release()
+	converters := newArrowValueConverters(rows.FieldDescriptions(), recordBuilder.Schema())
 
 	var recordCounter int
-	for rows.Next() {
+	for limiter.next(rows) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		values, err := rows.Values()
 		if err != nil {
-			details := err.Error()
-			apiErr := errors.NewAPIError(http.StatusInternalServerError, "Error reading row values", &details)
-			HandleError(w, apiErr)
-			return
+			return err
 		}
 
-		if err := appendArrowValues(recordBuilder, values); err != nil {
+		if err := appendArrowValues(recordBuilder, converters, values); err != nil {
 			details := err.Error()
-			err := errors.NewAPIError(http.StatusInternalServerError, "Error appending arrow value", &details)
-			HandleError(w, err)
-			return
+			return errors.NewAPIError(http.StatusInternalServerError, "Error appending arrow value", &details)
 		}
 
 		recordCounter++
 
 		if recordCounter >= batchSize {
-			record := recordBuilder.NewRecord()
-
-			if err := arrWriter.Write(record); err != nil {
-				details := err.Error()
-				apiErr := errors.NewAPIError(http.StatusInternalServerError, "Error writing record batch", &details)
-				HandleError(w, apiErr)
-				return
+			if err := writeRecord(); err != nil {
+				return err
 			}
-
-			record.Release()
 			recordCounter = 0
 		}
 	}
 
-	if recordCounter > 0 {
-		record := recordBuilder.NewRecord()
-		defer record.Release()
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-		if err := arrWriter.Write(record); err != nil {
-			details := err.Error()
-			apiErr := errors.NewAPIError(http.StatusInternalServerError, "Error writing record batch", &details)
-			HandleError(w, apiErr)
-		}
+	if recordCounter > 0 {
+		return writeRecord()
 	}
+
+	return nil
 }
 
 // getBodyData reads the request body from the provided http.Request and returns
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/endian"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/jobs"
+	"github.com/sogelink-research/pgrest/service"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+const (
+	spoolBatchSize = 10000 // Rows per record batch of a spooled result
+
+	// Field metadata keys storing the PostgreSQL type of the columns in a spooled result
+	spoolOIDKey          = "pgrest.oid"
+	spoolTypeModifierKey = "pgrest.typmod"
+)
+
+// spoolQueryResult executes the query of a job and writes the result as Arrow IPC stream to the writer,
+// reporting the spooled rows and bytes as progress of the job. The maxRows and maxResponseBytes limits
+// are enforced on the spooled result, the size of the Arrow stream is counted as response bytes.
+func spoolQueryResult(ctx context.Context, job *jobs.Job, w io.Writer, connection *settings.ConnectionConfig, query string, args []interface{}, options service.QueryOptions, limits settings.LimitsConfig) error {
+	rows, _, err := service.QueryPostgres(ctx, query, args, connection, options)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = contextError(ctx.Err())
+		}
+		return err
+	}
+	defer rows.Close()
+
+	schema := createSpoolSchema(rows.FieldDescriptions())
+
+	counter := &countingWriter{writer: w}
+	limiter := newResultLimiter(limits, counter)
+	arrWriter := ipc.NewWriter(counter, ipc.WithSchema(schema))
+
+	recordBuilder := createRecordBuilder(schema)
+	defer recordBuilder.Release()
+
+	err = writeArrowRecords(ctx, rows, recordBuilder, spoolBatchSize, limiter, func(record arrow.Record) error {
+		written := counter.written
+		if err := arrWriter.Write(record); err != nil {
+			details := err.Error()
+			return errors.NewAPIError(http.StatusInternalServerError, "Error writing record batch", &details)
+		}
+		job.AddProgress(record.NumRows(), counter.written-written)
+		return nil
+	})
+	if err != nil {
+		return resultError(ctx, err)
+	}
+
+	if err := arrWriter.Close(); err != nil {
+		details := err.Error()
+		return errors.NewAPIError(http.StatusInternalServerError, "Error closing Arrow writer", &details)
+	}
+
+	job.SetTruncated(limiter.truncated)
+	return nil
+}
+
+// createSpoolSchema creates the Arrow schema of a spooled result, the PostgreSQL type of the columns
+// is stored in the field metadata to restore the column descriptions when reading the result.
+func createSpoolSchema(columns []pgconn.FieldDescription) *arrow.Schema {
+	fields := createArrowSchema(columns, false).Fields()
+	for i, col := range columns {
+		fields[i].Metadata = arrow.NewMetadata(
+			[]string{spoolOIDKey, spoolTypeModifierKey},
+			[]string{strconv.FormatUint(uint64(col.DataTypeOID), 10), strconv.FormatInt(int64(col.TypeModifier), 10)},
+		)
+	}
+
+	return arrow.NewSchemaWithEndian(fields, nil, endian.NativeEndian)
+}
+
+// spooledRows reads a spooled result as pgx.Rows, so it can be written in all formats of the query endpoint.
+// The values are returned as the types returned by pgx for the PostgreSQL type of the column.
+type spooledRows struct {
+	file   *os.File
+	reader *ipc.Reader
+	fields []pgconn.FieldDescription
+	record arrow.Record
+	index  int
+	err    error
+}
+
+// newSpooledRows opens the spooled result in the file, the file is closed when the rows are closed.
+func newSpooledRows(file *os.File) (*spooledRows, error) {
+	reader, err := ipc.NewReader(file)
+	if err != nil {
+		file.Close()
+		details := err.Error()
+		return nil, errors.NewAPIError(http.StatusInternalServerError, "Error reading job result", &details)
+	}
+
+	fields := make([]pgconn.FieldDescription, reader.Schema().NumFields())
+	for i, field := range reader.Schema().Fields() {
+		fields[i] = pgconn.FieldDescription{Name: field.Name, Format: pgtype.TextFormatCode}
+		if value, ok := field.Metadata.GetValue(spoolOIDKey); ok {
+			oid, _ := strconv.ParseUint(value, 10, 32)
+			fields[i].DataTypeOID = uint32(oid)
+		}
+		if value, ok := field.Metadata.GetValue(spoolTypeModifierKey); ok {
+			typeModifier, _ := strconv.ParseInt(value, 10, 32)
+			fields[i].TypeModifier = int32(typeModifier)
+		}
+	}
+
+	return &spooledRows{file: file, reader: reader, fields: fields}, nil
+}
+
+func (r *spooledRows) Close() {
+	r.reader.Release()
+	r.file.Close()
+}
+
+func (r *spooledRows) Err() error {
+	return r.err
+}
+
+func (r *spooledRows) CommandTag() pgconn.CommandTag {
+	return pgconn.CommandTag{}
+}
+
+func (r *spooledRows) FieldDescriptions() []pgconn.FieldDescription {
+	return r.fields
+}
+
+// Next advances to the next row, reading the next record batch when all rows of the current batch are read.
+func (r *spooledRows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	for r.record == nil || r.index+1 >= int(r.record.NumRows()) {
+		if !r.reader.Next() {
+			if err := r.reader.Err(); err != nil && err != io.EOF {
+				details := err.Error()
+				r.err = errors.NewAPIError(http.StatusInternalServerError, "Error reading job result", &details)
+			}
+			r.record = nil
+			return false
+		}
+		r.record = r.reader.Record()
+		r.index = -1
+	}
+
+	r.index++
+	return true
+}
+
+func (r *spooledRows) Scan(dest ...interface{}) error {
+	return fmt.Errorf("scan is not supported on a spooled result")
+}
+
+// Values returns the values of the current row.
+func (r *spooledRows) Values() ([]interface{}, error) {
+	values := make([]interface{}, len(r.fields))
+	for i, field := range r.fields {
+		value, err := spooledValue(r.record.Column(i), r.index, field.DataTypeOID)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (r *spooledRows) RawValues() [][]byte {
+	return nil
+}
+
+func (r *spooledRows) Conn() *pgx.Conn {
+	return nil
+}
+
+// spooledValue returns the value at index i of the Arrow array as the type returned by pgx
+// for the PostgreSQL type with the given OID, the reverse of the arrowValueConverter.
+func spooledValue(arr arrow.Array, i int, oid uint32) (interface{}, error) {
+	if arr.IsNull(i) {
+		return nil, nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Int16:
+		return a.Value(i), nil
+	case *array.Int32:
+		return a.Value(i), nil
+	case *array.Int64:
+		return a.Value(i), nil
+	case *array.Uint32:
+		return a.Value(i), nil
+	case *array.Float32:
+		return a.Value(i), nil
+	case *array.Float64:
+		return a.Value(i), nil
+	case *array.Boolean:
+		return a.Value(i), nil
+	case *array.Binary:
+		return append([]byte(nil), a.Value(i)...), nil
+	case *array.FixedSizeBinary:
+		var uuid [16]byte
+		copy(uuid[:], a.Value(i))
+		return uuid, nil
+	case *array.Decimal128:
+		scale := a.DataType().(*arrow.Decimal128Type).Scale
+		return pgtype.Numeric{Int: a.Value(i).BigInt(), Exp: -scale, Valid: true}, nil
+	case *array.Decimal256:
+		scale := a.DataType().(*arrow.Decimal256Type).Scale
+		return pgtype.Numeric{Int: a.Value(i).BigInt(), Exp: -scale, Valid: true}, nil
+	case *array.Date32:
+		return a.Value(i).ToTime(), nil
+	case *array.Time64:
+		return pgtype.Time{Microseconds: int64(a.Value(i)), Valid: true}, nil
+	case *array.Timestamp:
+		// pgx decodes timestamps with time zone in the local time zone, timestamps without time zone as UTC
+		if a.DataType().(*arrow.TimestampType).TimeZone != "" {
+			return time.UnixMicro(int64(a.Value(i))), nil
+		}
+		return time.UnixMicro(int64(a.Value(i))).UTC(), nil
+	case *array.MonthDayNanoInterval:
+		v := a.Value(i)
+		return pgtype.Interval{Months: v.Months, Days: v.Days, Microseconds: v.Nanoseconds / int64(time.Microsecond), Valid: true}, nil
+	case *array.String:
+		switch oid {
+		case pgtype.JSONOID, pgtype.JSONBOID:
+			var value interface{}
+			err := json.Unmarshal([]byte(a.Value(i)), &value)
+			return value, err
+		case pgtype.NumericOID:
+			// Numerics without decimal equivalent are stored as text
+			var value pgtype.Numeric
+			err := value.Scan(a.Value(i))
+			return value, err
+		default:
+			return a.Value(i), nil
+		}
+	case *array.List:
+		elementOID, _ := utils.PGArrayElementOID(oid)
+		start, end := a.ValueOffsets(i)
+		elements := a.ListValues()
+		values := make([]interface{}, 0, end-start)
+		for j := int(start); j < int(end); j++ {
+			value, err := spooledValue(elements, j, elementOID)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unsupported Arrow type %s in job result", arr.DataType())
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestSpooledValueRoundTrip(t *testing.T) {
+	for _, test := range arrowValueTests {
+		if test.wantErr {
+			continue
+		}
+
+		t.Run(test.name, func(t *testing.T) {
+			fields := []pgconn.FieldDescription{{Name: "value", DataTypeOID: test.oid, TypeModifier: test.typeModifier}}
+			schema := createSpoolSchema(fields)
+			recordBuilder := createRecordBuilder(schema)
+			defer recordBuilder.Release()
+
+			converters := newArrowValueConverters(fields, schema)
+			if err := appendArrowValues(recordBuilder, converters, []interface{}{test.value}); err != nil {
+				t.Fatalf("appendArrowValues returned error: %v", err)
+			}
+
+			record := recordBuilder.NewRecord()
+			defer record.Release()
+
+			value, err := spooledValue(record.Column(0), 0, test.oid)
+			if err != nil {
+				t.Fatalf("spooledValue returned error: %v", err)
+			}
+
+			// The spooled value converts to the same Arrow value as the value decoded by pgx
+			if test.want == nil {
+				if value != nil {
+					t.Errorf("spooledValue = %#v, want nil", value)
+				}
+				return
+			}
+			got, err := converters[0](value)
+			if err != nil {
+				t.Fatalf("converting spooled value %#v returned error: %v", value, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("spooled value %#v converts to %#v, want %#v", value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSpooledValueTypes(t *testing.T) {
+	tests := []struct {
+		name         string
+		oid          uint32
+		typeModifier int32
+		value        interface{}
+		want         interface{}
+	}{
+		{name: "numeric", oid: pgtype.NumericOID, typeModifier: numericTypeModifier(10, 2), value: numeric(12345, -3), want: numeric(1235, -2)},
+		{name: "numeric unconstrained", oid: pgtype.NumericOID, typeModifier: -1, value: numeric(12345, -3), want: numeric(12345, -3)},
+		{name: "date", oid: pgtype.DateOID, value: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "timestamp", oid: pgtype.TimestampOID, value: time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC), want: time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)},
+		{name: "uuid", oid: pgtype.UUIDOID, value: [16]byte{1, 15: 2}, want: [16]byte{1, 15: 2}},
+		{name: "json", oid: pgtype.JSONBOID, value: map[string]interface{}{"a": []interface{}{1.0, nil}}, want: map[string]interface{}{"a": []interface{}{1.0, nil}}},
+		{name: "text array", oid: pgtype.TextArrayOID, value: []interface{}{"a", nil}, want: []interface{}{"a", nil}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields := []pgconn.FieldDescription{{Name: "value", DataTypeOID: test.oid, TypeModifier: test.typeModifier}}
+			schema := createSpoolSchema(fields)
+			recordBuilder := createRecordBuilder(schema)
+			defer recordBuilder.Release()
+
+			if err := appendArrowValues(recordBuilder, newArrowValueConverters(fields, schema), []interface{}{test.value}); err != nil {
+				t.Fatalf("appendArrowValues returned error: %v", err)
+			}
+
+			record := recordBuilder.NewRecord()
+			defer record.Release()
+
+			got, err := spooledValue(record.Column(0), 0, test.oid)
+			if err != nil {
+				t.Fatalf("spooledValue returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("spooledValue = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSpooledRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result.arrow")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := createSpoolSchema(roundTripColumns)
+	recordBuilder := createRecordBuilder(schema)
+	defer recordBuilder.Release()
+
+	converters := newArrowValueConverters(roundTripColumns, schema)
+	for _, values := range roundTripValues {
+		if err := appendArrowValues(recordBuilder, converters, values); err != nil {
+			t.Fatalf("appendArrowValues returned error: %v", err)
+		}
+	}
+	record := recordBuilder.NewRecord()
+	defer record.Release()
+
+	writer := ipc.NewWriter(file, ipc.WithSchema(schema))
+	if err := writer.Write(record); err != nil {
+		t.Fatalf("writing the spooled result: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("closing the spooled result: %v", err)
+	}
+	file.Close()
+
+	file, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := newSpooledRows(file)
+	if err != nil {
+		t.Fatalf("newSpooledRows returned error: %v", err)
+	}
+	defer rows.Close()
+
+	for i, field := range rows.FieldDescriptions() {
+		want := roundTripColumns[i]
+		if field.Name != want.Name || field.DataTypeOID != want.DataTypeOID || field.TypeModifier != want.TypeModifier {
+			t.Errorf("field %d = %s %d %d, want %s %d %d", i, field.Name, field.DataTypeOID, field.TypeModifier, want.Name, want.DataTypeOID, want.TypeModifier)
+		}
+	}
+
+	// Writing the spooled rows gives the same record as writing the rows of the query
+	spooledBuilder := createRecordBuilder(schema)
+	defer spooledBuilder.Release()
+
+	count := 0
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			t.Fatalf("reading row %d: %v", count, err)
+		}
+		if err := appendArrowValues(spooledBuilder, newArrowValueConverters(rows.FieldDescriptions(), schema), values); err != nil {
+			t.Fatalf("appending spooled row %d: %v", count, err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("reading the spooled rows: %v", err)
+	}
+	if count != len(roundTripValues) {
+		t.Fatalf("got %d rows, want %d", count, len(roundTripValues))
+	}
+
+	spooled := spooledBuilder.NewRecord()
+	defer spooled.Release()
+	assertColumnsEqual(t, record, spooled)
+}
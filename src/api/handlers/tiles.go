@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/service"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// TileHandler handles the HTTP request for a Mapbox Vector Tile of a layer configured on the connection.
+// The tile is generated by PostGIS using ST_AsMVT, tiles outside the zoom range of the layer
+// and tiles without features result in a 204 No Content response.
+func TileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		ctx := r.Context()
+
+		connection, err := getConnection(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		layerName, z, x, y, err := utils.GetTileFromRequest(r)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		layer, err := connection.GetLayer(layerName)
+		if err != nil {
+			apiError := errors.NewAPIError(http.StatusNotFound, fmt.Sprintf("Requested layer '%s' not found", layerName), nil)
+			HandleError(w, apiError)
+			return
+		}
+
+		if z < layer.MinZoom || z > layer.MaxZoom {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		defer logContextDone(ctx, connection)
+
+		user := utils.GetUserFromRequest(r)
+		limits := settings.GetEffectiveLimits(connection, user)
+		options := service.NewQueryOptions(connection, user, limits)
+
+		tile, err := service.QueryTile(ctx, connection, layer, z, x, y, options)
+		if err != nil {
+			if ctx.Err() != nil {
+				err = contextError(ctx.Err())
+			}
+			HandleError(w, err)
+			return
+		}
+
+		if len(tile) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+
+		bw := bufio.NewWriter(w)
+		compressionWriter, closeWriter := newCompressionWriter(w, r, bw)
+		compressionWriter.Write(tile)
+		closeWriter()
+		bw.Flush()
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/models"
+	"github.com/sogelink-research/pgrest/service"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/transactions"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// isolationLevels maps the isolation levels of the request body to the pgx isolation levels.
+var isolationLevels = map[models.IsolationLevel]pgx.TxIsoLevel{
+	models.ReadCommitted:  pgx.ReadCommitted,
+	models.RepeatableRead: pgx.RepeatableRead,
+	models.Serializable:   pgx.Serializable,
+}
+
+// transactionInfo is the response of beginning a transaction.
+type transactionInfo struct {
+	ID                 string                `json:"id"` // Signed id to reference the transaction in subsequent requests
+	IsolationLevel     models.IsolationLevel `json:"isolationLevel"`
+	ReadOnly           bool                  `json:"readOnly"`
+	IdleTimeoutSeconds int                   `json:"idleTimeoutSeconds"`
+}
+
+// TransactionBeginHandler handles the HTTP request for beginning an interactive transaction.
+// The transaction holds a pooled connection until it is committed or rolled back, queries are executed
+// in the transaction by setting its id in the request body of the query and named query endpoints.
+// An unused transaction is rolled back after the idle timeout.
+func TransactionBeginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		connection, err := getConnection(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		var body models.TransactionRequestBody
+		bodyString := utils.GetBodyString(r)
+		if bodyString == "" {
+			bodyString = "{}"
+		}
+		if err := json.Unmarshal([]byte(bodyString), &body); err != nil {
+			details := err.Error()
+			HandleError(w, errors.NewAPIError(http.StatusBadRequest, "Invalid request body", &details))
+			return
+		}
+
+		user := utils.GetUserFromRequest(r)
+		limits := settings.GetEffectiveLimits(connection, user)
+		options := service.NewQueryOptions(connection, user, limits)
+		options.ReadOnly = options.ReadOnly || body.ReadOnly
+		transactionsConfig := config.PGRest.Transactions
+		transactionLimits := transactions.Limits{
+			MaxPerUser:  transactionsConfig.MaxPerUser,
+			IdleTimeout: time.Duration(transactionsConfig.IdleTimeoutSeconds) * time.Second,
+		}
+
+		txOptions := pgx.TxOptions{IsoLevel: isolationLevels[body.IsolationLevel]}
+		t, err := service.BeginTransaction(r.Context(), connection, txOptions, options, getClientID(r), transactionLimits)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+		defer t.Release()
+
+		info := transactionInfo{
+			ID:                 t.Token(),
+			IsolationLevel:     body.IsolationLevel,
+			ReadOnly:           options.ReadOnly,
+			IdleTimeoutSeconds: transactionsConfig.IdleTimeoutSeconds,
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/api/%s/transactions/%s", connection.Name, info.ID))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(info)
+	}
+}
+
+// TransactionCommitHandler handles the HTTP request for committing an interactive transaction.
+func TransactionCommitHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		t, err := acquireTransaction(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+		defer t.Release()
+
+		if err := service.CommitTransaction(r.Context(), t); err != nil {
+			HandleError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TransactionRollbackHandler handles the HTTP request for rolling back an interactive transaction.
+func TransactionRollbackHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := settings.GetConfig()
+
+		t, err := acquireTransaction(r, config)
+		if err != nil {
+			HandleError(w, err)
+			return
+		}
+		defer t.Release()
+
+		t.Close(context.Background(), false)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// acquireTransaction acquires the interactive transaction requested in the URL for the user of the request.
+// It returns an APIError if the transaction id is invalid, the transaction is not found or in use by another request.
+func acquireTransaction(r *http.Request, config settings.Config) (*transactions.Transaction, error) {
+	connection, err := getConnection(r, config)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GetTransactionFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions.Acquire(token, transactions.KindTransaction, connection.Name, getClientID(r))
+}
+
+// writeTransactionQueryResult executes the query with the given args inside the interactive transaction
+// and streams the result to the client in the requested format, like writeQueryResult.
+// The transaction is used by one request at a time, results are never cached.
+func writeTransactionQueryResult(w http.ResponseWriter, r *http.Request, connection *settings.ConnectionConfig, token string, query string, args []interface{}, format models.FormatType, formatOptions models.FormatOptions) {
+	ctx := r.Context()
+	defer logContextDone(ctx, connection)
+
+	limits := settings.GetEffectiveLimits(connection, utils.GetUserFromRequest(r))
+	setLimitHeaders(w, limits)
+
+	t, err := transactions.Acquire(token, transactions.KindTransaction, connection.Name, getClientID(r))
+	if err != nil {
+		HandleError(w, err)
+		return
+	}
+	defer t.Release()
+
+	// The session settings are applied when the transaction begins, on connections with row-level
+	// security only named queries can change them as ad-hoc SQL is rejected
+	options := service.QueryOptions{ReadOnly: connection.ReadOnly}
+	rows, columns, err := service.QueryTransaction(ctx, t, query, args, options)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = contextError(ctx.Err())
+		}
+		HandleError(w, err)
+		return
+	}
+
+	defer rows.Close()
+
+	writeRows(w, r, rows, columns, connection, limits, format, formatOptions, nil)
+}
@@ -10,22 +10,28 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/sogelink-research/pgrest/api/handlers"
 	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/jobs"
 	"github.com/sogelink-research/pgrest/settings"
 	"github.com/sogelink-research/pgrest/utils"
 )
 
 // AuthMiddleware is a middleware function that handles authentication for API requests.
-// It takes a `config` parameter of type `settings.Config` which contains the configuration settings.
+// The users and connections of the current configuration are used, so they can be changed by reloading the configuration.
 // The function returns a `func(http.Handler) http.Handler` which can be used as middleware in the API router.
 // The middleware validates the authentication token, checks if the requested connection is accessible by the user,
+// or the requested named query when the user only has access to specific queries,
 // and performs additional origin checks for security.
+// For GET and DELETE requests, e.g. vector tiles and jobs, the request URI is signed instead of the body.
 // If the authentication is successful, the middleware calls the next handler in the chain.
 // If any error occurs during the authentication process, it returns an appropriate error response.
-func AuthMiddleware(config settings.Config) func(http.Handler) http.Handler {
+func AuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			config := settings.GetConfig()
+
 			connectionName, err := utils.GetConnectionNameFromRequest(r)
 			if err != nil {
 				handlers.HandleError(w, err)
@@ -46,8 +52,12 @@ func AuthMiddleware(config settings.Config) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Get the request body data
+			// Get the signed request content, GET and DELETE requests have no body so the
+			// request URI (path and query string) is signed instead
 			bodyString := utils.GetBodyString(r)
+			if r.Method == http.MethodGet || r.Method == http.MethodDelete {
+				bodyString = r.URL.RequestURI()
+			}
 
 			// Get the request time
 			requestTime, err := getRequestTimeHeader(r)
@@ -88,11 +98,15 @@ func AuthMiddleware(config settings.Config) func(http.Handler) http.Handler {
 				return
 			}
 
-			// if connection.Name is not in the user's connections
-			if !utils.Contains(user.Connections, connection.Name) {
-				apiError := errors.NewAPIError(http.StatusUnauthorized, "User has not access to requested connection", nil)
-				handlers.HandleError(w, apiError)
-				return
+			// if connection.Name is not in the user's connections, the user
+			// might still have access to the requested named query
+			if !user.HasConnectionAccess(connection.Name) {
+				queryName := chi.URLParam(r, "name")
+				if queryName == "" || !user.HasQueryAccess(connection.Name, queryName) {
+					apiError := errors.NewAPIError(http.StatusUnauthorized, "User has not access to requested connection", nil)
+					handlers.HandleError(w, apiError)
+					return
+				}
 			}
 
 			// Additional origin check when send from backend
@@ -104,8 +118,39 @@ func AuthMiddleware(config settings.Config) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Call the next handler
+			// Call the next handler with the authenticated user
+			next.ServeHTTP(w, utils.SetUserInRequest(r, user))
+		})
+	}
+}
+
+// JobAuthMiddleware is a middleware function that handles authentication for requests on a job.
+// The job is authenticated as request on the connection of the job using AuthMiddleware,
+// additionally only the user who submitted the job has access to it.
+// Unknown jobs and jobs of other users result in a not found error.
+func JobAuthMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		auth := AuthMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			job, ok := jobs.Get(chi.URLParam(r, "id"))
+			user := utils.GetUserFromRequest(r)
+			if !ok || (job.ClientID != "" && (user == nil || user.ClientID != job.ClientID)) {
+				handlers.HandleError(w, errors.NewAPIError(http.StatusNotFound, "Job not found", nil))
+				return
+			}
+
 			next.ServeHTTP(w, r)
+		}))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			job, ok := jobs.Get(chi.URLParam(r, "id"))
+			if !ok {
+				handlers.HandleError(w, errors.NewAPIError(http.StatusNotFound, "Job not found", nil))
+				return
+			}
+
+			// Authenticate the request on the connection of the job
+			chi.RouteContext(r.Context()).URLParams.Add("connection", job.Connection)
+			auth.ServeHTTP(w, r)
 		})
 	}
 }
@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sogelink-research/pgrest/settings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const entryExtension = ".cache" // Extension of the cached results stored on disk
+
+// entryFilePattern matches the names of the cached results stored on disk, the cache key with a random suffix.
+var entryFilePattern = regexp.MustCompile(`^[0-9a-f]{64}-[0-9]+` + regexp.QuoteMeta(entryExtension) + `$`)
+
+var (
+	entries    = make(map[string]*Entry) // Map to store the cache entries by key
+	lru        = list.New()              // Cache entries ordered from most to least recently used
+	totalBytes int64                     // The total size of the cached results
+	cacheMutex sync.Mutex                // Mutex to ensure thread safety for the cache
+	config     settings.ResultCacheConfig
+)
+
+// Entry is a cached query result, the rendered and uncompressed response body of a request.
+type Entry struct {
+	Key         string
+	ETag        string // Weak entity tag of the result
+	ContentType string
+	Truncated   string // The limit which truncated the result, if any
+	Created     time.Time
+	Expires     time.Time
+	Size        int64
+
+	data    []byte // The result when stored in memory
+	path    string // The file of the result when stored on disk
+	element *list.Element
+}
+
+// Init sets the configuration of the result cache. When a directory is configured the
+// results are stored on disk, the results left by a previous run are removed and other files are kept.
+func Init(cacheConfig settings.ResultCacheConfig) error {
+	if cacheConfig.Dir != "" {
+		if err := os.MkdirAll(cacheConfig.Dir, 0o700); err != nil {
+			return fmt.Errorf("error creating cache directory: %w", err)
+		}
+
+		files, err := os.ReadDir(cacheConfig.Dir)
+		if err != nil {
+			return fmt.Errorf("error reading cache directory: %w", err)
+		}
+		for _, file := range files {
+			if !file.IsDir() && entryFilePattern.MatchString(file.Name()) {
+				os.Remove(filepath.Join(cacheConfig.Dir, file.Name()))
+			}
+		}
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	config = cacheConfig
+	entries = make(map[string]*Entry)
+	lru.Init()
+	totalBytes = 0
+
+	return nil
+}
+
+// MaxEntryBytes returns the maximum size of a result to cache, results of 0 bytes are never cached.
+func MaxEntryBytes() int64 {
+	return min(config.MaxEntryBytes, config.MaxBytes)
+}
+
+// Key returns the cache key of the given parts, e.g. the connection, query, params and format.
+// The parts are JSON encoded including their type, so e.g. an int4 and float8 param 1 result in different keys.
+func Key(parts ...interface{}) string {
+	h := sha256.New()
+	encoder := json.NewEncoder(h)
+	for _, part := range parts {
+		fmt.Fprintf(h, "%T:", part)
+		if err := encoder.Encode(part); err != nil {
+			fmt.Fprintf(h, "%v\n", part)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the entry with the given key when it is cached and not expired.
+func Get(key string) (*Entry, bool) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	entry, ok := entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.Expires) {
+		remove(entry)
+		return nil, false
+	}
+
+	lru.MoveToFront(entry.element)
+	return entry, true
+}
+
+// Put caches the result with the given key for the ttl, replacing an existing entry.
+// The least recently used entries are evicted to stay within the byte budget.
+// It returns the ETag of the cached result, or an empty string when the result is not cached.
+func Put(key string, data []byte, contentType string, truncated string, ttl time.Duration) string {
+	size := int64(len(data))
+	if size == 0 || size > MaxEntryBytes() {
+		return ""
+	}
+
+	now := time.Now()
+	hash := sha256.Sum256(data)
+	entry := &Entry{
+		Key:         key,
+		ETag:        fmt.Sprintf(`W/"%s"`, hex.EncodeToString(hash[:16])),
+		ContentType: contentType,
+		Truncated:   truncated,
+		Created:     now,
+		Expires:     now.Add(ttl),
+		Size:        size,
+	}
+
+	if config.Dir != "" {
+		// Write to a temporary file, so readers of the replaced entry are not affected
+		file, err := os.CreateTemp(config.Dir, key+"-*"+entryExtension)
+		if err == nil {
+			_, err = file.Write(data)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(file.Name())
+			}
+		}
+		if err != nil {
+			log.Errorf("Error writing cached result: %v", err)
+			return ""
+		}
+		entry.path = file.Name()
+	} else {
+		entry.data = data
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	if existing, ok := entries[key]; ok {
+		remove(existing)
+	}
+
+	for totalBytes+size > config.MaxBytes && lru.Len() > 0 {
+		remove(lru.Back().Value.(*Entry))
+	}
+
+	entry.element = lru.PushFront(entry)
+	entries[key] = entry
+	totalBytes += size
+
+	return entry.ETag
+}
+
+// remove removes the entry from the cache, the cache mutex must be held.
+func remove(entry *Entry) {
+	delete(entries, entry.Key)
+	lru.Remove(entry.element)
+	totalBytes -= entry.Size
+
+	if entry.path != "" {
+		os.Remove(entry.path)
+	}
+}
+
+// Open opens the cached result for reading.
+func (e *Entry) Open() (io.ReadCloser, error) {
+	if e.path != "" {
+		return os.Open(e.path)
+	}
+	return io.NopCloser(bytes.NewReader(e.data)), nil
+}
+
+// Age returns the time since the result was cached.
+func (e *Entry) Age() time.Duration {
+	return time.Since(e.Created)
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+func TestInitRemovesOnlyCachedResults(t *testing.T) {
+	dir := t.TempDir()
+	config := settings.ResultCacheConfig{MaxBytes: 1024, MaxEntryBytes: 1024, Dir: dir}
+	if err := Init(config); err != nil {
+		t.Fatal(err)
+	}
+
+	// A result stored by a previous run
+	key := Key("default", "SELECT 1")
+	if Put(key, []byte("[]"), "application/json", "", time.Minute) == "" {
+		t.Fatal("result was not cached")
+	}
+	stored, err := filepath.Glob(filepath.Join(dir, "*"+entryExtension))
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("cached results on disk = %v, want one", stored)
+	}
+
+	kept := []string{"report.cache", key + entryExtension, "notes.txt"}
+	for _, name := range kept {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Init(config); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	if _, err := os.Stat(stored[0]); !os.IsNotExist(err) {
+		t.Errorf("cached result %s of the previous run was not removed", filepath.Base(stored[0]))
+	}
+	for _, name := range kept {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was removed", name)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+// checkConfig validates the configuration file given by `pgrest config check [file]`,
+// it prints the problems found and exits with status 1 when the configuration is invalid.
+func checkConfig(args []string) {
+	flags := flag.NewFlagSet("config check", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: pgrest config check [file]")
+		fmt.Fprintln(flags.Output(), "Validates the configuration file, default PGREST_CONFIG_PATH or ../config/pgrest.conf.")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	file := settings.GetConfigFile()
+	if flags.NArg() == 1 {
+		file = flags.Arg(0)
+	}
+
+	if _, err := settings.LoadConfig(file); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s: configuration is valid\n", file)
+}
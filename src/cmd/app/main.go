@@ -1,34 +1,68 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strings"
+)
 
-	"github.com/sogelink-research/pgrest/server"
-	"github.com/sogelink-research/pgrest/settings"
+const usage = `PGRest serves PostgreSQL queries over HTTP.
 
-	log "github.com/sirupsen/logrus"
-)
+Usage:
+  pgrest <command> [flags]
+
+Commands:
+  serve          Start the server (default)
+  config check   Validate a configuration file
+  user add       Generate the credentials of a new user
+  user rotate    Generate a new secret for an existing user
+  version        Print the version and build information
+
+Run 'pgrest <command> -h' for the flags of a command.
+`
+
+func main() {
+	args := os.Args[1:]
 
-func initLogger(config settings.Config) {
-	log.SetOutput(os.Stdout)
-	log.SetLevel(log.InfoLevel)
-	if config.PGRest.Debug {
-		log.SetLevel(log.DebugLevel)
+	// Without a command the server is started, flags are passed to serve
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !isHelp(args[0])) {
+		serve(args)
+		return
 	}
 
-	log.SetFormatter(&log.TextFormatter{
-		DisableColors: false,
-		FullTimestamp: true,
-	})
+	command, args := args[0], args[1:]
+	switch command {
+	case "serve":
+		serve(args)
+	case "config":
+		runSubcommand("config", args, map[string]func([]string){"check": checkConfig})
+	case "user":
+		runSubcommand("user", args, map[string]func([]string){"add": addUser, "rotate": rotateUser})
+	case "version":
+		printVersion(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n%s", command, usage)
+		os.Exit(2)
+	}
 }
 
-func main() {
-	err := settings.InitializeConfig()
-	if err != nil {
-		log.Fatalf("Failed to initialize configuration: %v", err)
+// runSubcommand runs the subcommand of the command given as first argument, e.g. check of `pgrest config check`.
+func runSubcommand(command string, args []string, subcommands map[string]func([]string)) {
+	if len(args) > 0 {
+		if run, ok := subcommands[args[0]]; ok {
+			run(args[1:])
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Unknown command '%s %s'\n\n", command, args[0])
 	}
 
-	config := settings.GetConfig()
-	initLogger(config)
-	server.Start(config)
+	fmt.Fprint(os.Stderr, usage)
+	os.Exit(2)
+}
+
+// isHelp returns true if the argument is a help flag.
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sogelink-research/pgrest/server"
+	"github.com/sogelink-research/pgrest/settings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func initLogger(config settings.Config) {
+	log.SetOutput(os.Stdout)
+	log.SetLevel(config.PGRest.GetLogLevel())
+
+	log.SetFormatter(&log.TextFormatter{
+		DisableColors: false,
+		FullTimestamp: true,
+	})
+}
+
+// serve starts the server, the flags override the settings of the configuration file.
+// The overrides are kept when the configuration is reloaded.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: pgrest serve [flags]")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", "", "The configuration file, default PGREST_CONFIG_PATH or ../config/pgrest.conf")
+	port := flags.Int("port", 0, "The HTTP port, overrides pgrest.port")
+	logLevel := flags.String("log-level", "", "The log level (trace, debug, info, warn or error), overrides pgrest.logLevel")
+	flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *configPath != "" {
+		settings.SetConfigFile(*configPath)
+	}
+	if *port != 0 {
+		settings.SetOverride("PGREST_PORT", strconv.Itoa(*port))
+	}
+	if *logLevel != "" {
+		settings.SetOverride("PGREST_LOGLEVEL", *logLevel)
+	}
+
+	err := settings.InitializeConfig()
+	if err != nil {
+		exit(fmt.Errorf("failed to initialize configuration %s: %v", settings.GetConfigFile(), err))
+	}
+
+	config := settings.GetConfig()
+	initLogger(config)
+	log.Infof("Starting PGRest %s using configuration %s", version, settings.GetConfigFile())
+	server.Start(config)
+}
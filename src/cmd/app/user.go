@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sogelink-research/pgrest/settings"
+	"gopkg.in/yaml.v3"
+)
+
+// userSnippet is the configuration of a user printed by the user commands.
+type userSnippet struct {
+	ClientID     string   `json:"clientId" yaml:"clientId" toml:"clientId"`
+	ClientSecret string   `json:"clientSecret" yaml:"clientSecret" toml:"clientSecret"`
+	Connections  []string `json:"connections,omitempty" yaml:"connections,omitempty" toml:"connections,omitempty"`
+}
+
+// addUser generates the credentials of a new user given by `pgrest user add` and prints the configuration of the user.
+// The client id and connections are checked against the configuration file when it can be loaded.
+func addUser(args []string) {
+	flags := flag.NewFlagSet("user add", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: pgrest user add [flags]")
+		flags.PrintDefaults()
+	}
+	clientID := flags.String("client-id", "", "The client id, default a generated id")
+	connections := flags.String("connections", "", "Comma separated connections the user has access to")
+	format := flags.String("format", "json", "The format of the printed configuration (json, yaml or toml)")
+	configPath := flags.String("config", settings.GetConfigFile(), "The configuration file the user is added to")
+	flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	user := userSnippet{ClientID: *clientID, ClientSecret: generateSecret()}
+	if user.ClientID == "" {
+		user.ClientID = generateClientID()
+	}
+	for _, connection := range strings.Split(*connections, ",") {
+		if connection = strings.TrimSpace(connection); connection != "" {
+			user.Connections = append(user.Connections, connection)
+		}
+	}
+
+	if config, err := settings.LoadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: user not checked against %s: %v\n", *configPath, err)
+	} else {
+		if _, ok := config.UsersLookup[user.ClientID]; ok {
+			exit(fmt.Errorf("user '%s' already exists in %s, use pgrest user rotate to generate a new secret", user.ClientID, *configPath))
+		}
+		for _, connection := range user.Connections {
+			if _, err := config.GetConnectionConfig(connection); err != nil {
+				exit(fmt.Errorf("unknown connection '%s' in %s", connection, *configPath))
+			}
+		}
+	}
+
+	printUser(user, *format)
+	fmt.Fprintf(os.Stderr, "Add the user to the users of %s.\n", *configPath)
+}
+
+// rotateUser generates a new secret for an existing user given by `pgrest user rotate <clientId>`
+// and prints the configuration of the user.
+func rotateUser(args []string) {
+	flags := flag.NewFlagSet("user rotate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: pgrest user rotate [flags] <clientId>")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "json", "The format of the printed configuration (json, yaml or toml)")
+	configPath := flags.String("config", settings.GetConfigFile(), "The configuration file of the user")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	config, err := settings.LoadConfig(*configPath)
+	if err != nil {
+		exit(fmt.Errorf("%s: %v", *configPath, err))
+	}
+
+	existing, ok := config.UsersLookup[flags.Arg(0)]
+	if !ok {
+		exit(fmt.Errorf("user '%s' not found in %s", flags.Arg(0), *configPath))
+	}
+
+	user := userSnippet{ClientID: existing.ClientID, ClientSecret: generateSecret(), Connections: existing.Connections}
+	printUser(user, *format)
+	fmt.Fprintf(os.Stderr, "Replace the clientSecret of the user in %s and reload the configuration.\n", *configPath)
+}
+
+// printUser prints the configuration of the user in the given format,
+// as element of the users list in JSON and YAML and as users table in TOML.
+func printUser(user userSnippet, format string) {
+	var content []byte
+	var err error
+
+	switch format {
+	case "json":
+		content, err = json.MarshalIndent(user, "", "  ")
+		content = append(content, '\n')
+	case "yaml":
+		content, err = yaml.Marshal([]userSnippet{user})
+	case "toml":
+		var buffer bytes.Buffer
+		err = toml.NewEncoder(&buffer).Encode(map[string][]userSnippet{"users": {user}})
+		content = buffer.Bytes()
+	default:
+		err = fmt.Errorf("unsupported format '%s', use json, yaml or toml", format)
+	}
+
+	if err != nil {
+		exit(err)
+	}
+	os.Stdout.Write(content)
+}
+
+// generateClientID returns a random client id.
+func generateClientID() string {
+	return "client-" + hex.EncodeToString(randomBytes(8))
+}
+
+// generateSecret returns a random secret of 256 bits.
+func generateSecret() string {
+	return base64.RawURLEncoding.EncodeToString(randomBytes(32))
+}
+
+func randomBytes(n int) []byte {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		exit(fmt.Errorf("error generating random bytes: %v", err))
+	}
+	return bytes
+}
+
+// exit prints the error and exits with status 1.
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// The build information, set at link time, e.g.
+// go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%FT%TZ)" ./cmd/app
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// printVersion prints the version and build information given by `pgrest version`.
+// The commit and date embedded by the Go toolchain are used when they are not set at link time.
+func printVersion(args []string) {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: pgrest version")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	revision, buildDate := commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && revision == "" {
+				revision = setting.Value
+			}
+			if setting.Key == "vcs.time" && buildDate == "" {
+				buildDate = setting.Value
+			}
+		}
+	}
+
+	fmt.Printf("pgrest %s\n", version)
+	if revision != "" {
+		fmt.Printf("  commit: %s\n", revision)
+	}
+	if buildDate != "" {
+		fmt.Printf("  built:  %s\n", buildDate)
+	}
+	fmt.Printf("  go:     %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
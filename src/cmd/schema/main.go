@@ -0,0 +1,214 @@
+// Command schema generates the JSON Schema of the PGRest configuration, used by editors to validate
+// and complete JSON, YAML and TOML configuration files. The schema is generated from the types of the
+// settings package, the descriptions are the comments of the types and fields in its source.
+//
+//	go run ./cmd/schema -src ./settings -out ../config/pgrest.schema.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+// referenceSchema matches the ${...} references which are converted to the type of the setting when loading the configuration.
+var referenceSchema = map[string]interface{}{"type": "string", "pattern": `\$\{[^}]*\}`}
+
+// enums contains the allowed values of settings, e.g. "ConnectionConfig.Auth".
+var enums = map[string][]string{
+	"ConnectionConfig.Auth": {"private", "public"},
+	"PGRestConfig.LogLevel": settings.LogLevels,
+}
+
+// required contains the settings required by the validation of the configuration.
+var required = map[string][]string{
+	"ConnectionConfig": {"name", "connectionString"},
+	"NamedQueryConfig": {"name", "query"},
+	"TileLayerConfig":  {"name"},
+	"UserConfig":       {"clientId", "clientSecret"},
+}
+
+type generator struct {
+	comments map[string]string // The comments of the types and fields, e.g. "ConnectionConfig.Auth"
+	defs     map[string]interface{}
+}
+
+func main() {
+	source := flag.String("src", ".", "The directory of the source of the settings package")
+	output := flag.String("out", "pgrest.schema.json", "The file the schema is written to")
+	flag.Parse()
+
+	comments, err := readComments(*source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading comments: %v\n", err)
+		os.Exit(1)
+	}
+
+	g := &generator{comments: comments, defs: make(map[string]interface{})}
+	schema := g.object(reflect.TypeOf(settings.Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "PGRest configuration"
+	schema["$defs"] = g.defs
+	schema["properties"].(map[string]interface{})["$schema"] = map[string]interface{}{
+		"type":        "string",
+		"description": "The JSON Schema of the configuration",
+	}
+
+	content, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*output, append(content, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing schema: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// schema returns the schema of a setting of type t.
+// Numbers, booleans and lists of strings can also be set using a ${...} reference.
+func (g *generator) schema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return anyOf(map[string]interface{}{"type": "boolean"})
+	case reflect.Int, reflect.Int64:
+		return anyOf(map[string]interface{}{"type": "integer"})
+	case reflect.Slice:
+		schema := map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+		if t.Elem().Kind() == reflect.String {
+			return anyOf(schema) // A reference is converted to a comma separated list
+		}
+		return schema
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // Reserve the name, the definition may reference itself
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	panic(fmt.Sprintf("unsupported setting type %s", t))
+}
+
+// object returns the schema of the JSON object of the struct type t.
+func (g *generator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	g.properties(t, properties)
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if comment := g.comments[t.Name()]; comment != "" {
+		schema["description"] = comment
+	}
+	if names := required[t.Name()]; len(names) > 0 {
+		schema["required"] = names
+	}
+	return schema
+}
+
+// properties adds the schemas of the JSON fields of the struct type t, including the fields of embedded structs.
+func (g *generator) properties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			g.properties(field.Type, properties)
+			continue
+		}
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		key := t.Name() + "." + field.Name
+		schema := g.schema(field.Type)
+		if values := enums[key]; len(values) > 0 {
+			schema = map[string]interface{}{"enum": values}
+		}
+		if comment := g.comments[key]; comment != "" {
+			schema["description"] = comment
+		}
+		properties[name] = schema
+	}
+}
+
+// anyOf returns a schema matching the schema or a ${...} reference.
+func anyOf(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"anyOf": []interface{}{schema, referenceSchema}}
+}
+
+// readComments returns the doc and line comments of the types and struct fields in the Go files of the directory.
+func readComments(dir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make(map[string]string)
+	fileSet := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		parsed, err := parser.ParseFile(fileSet, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		ast.Inspect(parsed, func(node ast.Node) bool {
+			decl, ok := node.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE {
+				return true
+			}
+
+			for _, spec := range decl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				comments[typeSpec.Name.Name] = commentText(decl.Doc, typeSpec.Doc)
+
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				for _, field := range structType.Fields.List {
+					for _, name := range field.Names {
+						comments[typeSpec.Name.Name+"."+name.Name] = commentText(field.Doc, field.Comment)
+					}
+				}
+			}
+			return false
+		})
+	}
+	return comments, nil
+}
+
+// commentText returns the text of the first non-empty comment group as one line.
+func commentText(groups ...*ast.CommentGroup) string {
+	for _, group := range groups {
+		if text := strings.Join(strings.Fields(group.Text()), " "); text != "" {
+			return text
+		}
+	}
+	return ""
+}
@@ -24,13 +24,21 @@ func init() {
 }
 
 // periodicCleanup is a goroutine that periodically cleans up idle database connection pools.
-// It closes idle pools that have not been used for a certain duration specified by cleanupInterval.
+// It closes idle pools that have not been used for a certain duration specified by cleanupInterval,
+// pools with acquired connections are never closed.
 func periodicCleanup() {
 	for {
 		time.Sleep(cleanupInterval)
 
 		dbPoolMutex.Lock()
 		for name, pool := range dbPoolMap {
+			// Pools with acquired connections are in use, e.g. by a long running job,
+			// closing them would block until the connections are released
+			if pool.Stat().AcquiredConns() > 0 {
+				poolLastUsed[name] = time.Now()
+				continue
+			}
+
 			lastUsed, ok := poolLastUsed[name]
 			if !ok || time.Since(lastUsed) > cleanupInterval {
 				pool.Close()
@@ -57,6 +65,22 @@ func CloseDBPools() {
 	poolLastUsed = make(map[string]time.Time)
 }
 
+// ClosePool removes the pool with the given name, so the next request opens a new pool.
+// The pool is closed in the background, it waits until the acquired connections of
+// in-flight queries and open transactions are released.
+func ClosePool(name string) {
+	dbPoolMutex.Lock()
+	pool, ok := dbPoolMap[name]
+	delete(dbPoolMap, name)
+	delete(poolLastUsed, name)
+	dbPoolMutex.Unlock()
+
+	if ok {
+		log.Debugf("Closing database pool: %s", name)
+		go pool.Close()
+	}
+}
+
 // GetDBPool returns a database connection pool for the specified name and connection string.
 // If a pool with the given name already exists, it returns the existing pool.
 // Otherwise, it creates a new pool and adds it to the pool map.
@@ -72,19 +96,19 @@ func GetDBPool(name string, connectionString string) (*pgxpool.Pool, error) {
 
 	poolConfig, err := pgxpool.ParseConfig(connectionString)
 	if err != nil {
-		log.Fatalf("Unable to parse connection string: %v\n", err)
+		return nil, fmt.Errorf("unable to parse connection string of database '%s': %w", name, err)
 	}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 
 	if err != nil {
-		return nil, fmt.Errorf("error connecting to database '%s': %v", name, err)
+		return nil, fmt.Errorf("error connecting to database '%s': %w", name, err)
 	}
 
 	err = pool.Ping(context.Background())
 	if err != nil {
 		pool.Close()
-		return nil, fmt.Errorf("error connecting to database '%s': %v", name, err)
+		return nil, fmt.Errorf("error connecting to database '%s': %w", name, err)
 	}
 
 	log.Debugf("Opened new database pool: %s", name)
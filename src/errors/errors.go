@@ -4,10 +4,17 @@ import "net/http"
 
 // APIError represents an error returned by the API.
 type APIError struct {
-	StatusCode int     `json:"status"`            // The HTTP status code of the error.
-	StatusText string  `json:"statusText"`        // The HTTP status text of the error.
-	Message    string  `json:"error"`             // The error message.
-	Details    *string `json:"details,omitempty"` // Additional details about the error (optional).
+	StatusCode int     `json:"status"`               // The HTTP status code of the error.
+	StatusText string  `json:"statusText"`           // The HTTP status text of the error.
+	Message    string  `json:"error"`                // The error message.
+	Details    *string `json:"details,omitempty"`    // Additional details about the error (optional).
+	Code       string  `json:"code,omitempty"`       // The PostgreSQL SQLSTATE error code (optional).
+	Severity   string  `json:"severity,omitempty"`   // The PostgreSQL error severity (optional).
+	Position   int32   `json:"position,omitempty"`   // The position of the error in the query string (optional).
+	Hint       string  `json:"hint,omitempty"`       // A hint on how to solve the PostgreSQL error (optional).
+	Column     string  `json:"column,omitempty"`     // The column related to the PostgreSQL error (optional).
+	Constraint string  `json:"constraint,omitempty"` // The constraint related to the PostgreSQL error (optional).
+	Statement  int     `json:"statement,omitempty"`  // The number of the failed statement of a batch, starting at 1 (optional).
 }
 
 // Implement the Error method to satisfy the error interface
@@ -0,0 +1,97 @@
+package errors
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// sqlStateError is the HTTP status code and message returned for a PostgreSQL SQLSTATE error code or class.
+type sqlStateError struct {
+	statusCode int
+	message    string
+}
+
+// sqlStateErrors maps PostgreSQL SQLSTATE error codes to HTTP errors.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+var sqlStateErrors = map[string]sqlStateError{
+	"25006": {http.StatusForbidden, "Read-only violation"},                // read_only_sql_transaction
+	"42501": {http.StatusForbidden, "Permission denied"},                  // insufficient_privilege
+	"42P01": {http.StatusNotFound, "Table not found"},                     // undefined_table
+	"42883": {http.StatusNotFound, "Function not found"},                  // undefined_function
+	"3F000": {http.StatusNotFound, "Schema not found"},                    // invalid_schema_name
+	"57014": {http.StatusGatewayTimeout, "Query canceled"},                // query_canceled, e.g. statement_timeout
+	"55P03": {http.StatusConflict, "Lock not available"},                  // lock_not_available
+	"40001": {http.StatusConflict, "Serialization failure"},               // serialization_failure
+	"40P01": {http.StatusConflict, "Deadlock detected"},                   // deadlock_detected
+	"53300": {http.StatusServiceUnavailable, "Too many connections"},      // too_many_connections
+	"57P01": {http.StatusServiceUnavailable, "Database is shutting down"}, // admin_shutdown
+	"57P03": {http.StatusServiceUnavailable, "Database is not available"}, // cannot_connect_now
+	"28000": {http.StatusBadGateway, "Database authentication failed"},    // invalid_authorization_specification
+	"28P01": {http.StatusBadGateway, "Database authentication failed"},    // invalid_password
+	"3D000": {http.StatusBadGateway, "Database not found"},                // invalid_catalog_name
+	"54000": {http.StatusRequestEntityTooLarge, "Program limit exceeded"}, // program_limit_exceeded
+	"0A000": {http.StatusNotImplemented, "Feature not supported"},         // feature_not_supported
+	"P0001": {http.StatusBadRequest, "Exception raised"},                  // raise_exception
+	"22012": {http.StatusBadRequest, "Division by zero"},                  // division_by_zero
+	"42601": {http.StatusBadRequest, "Syntax error"},                      // syntax_error
+	"42703": {http.StatusBadRequest, "Column not found"},                  // undefined_column
+	"22P02": {http.StatusBadRequest, "Invalid text representation"},       // invalid_text_representation
+	"23505": {http.StatusConflict, "Unique violation"},                    // unique_violation
+	"23503": {http.StatusConflict, "Foreign key violation"},               // foreign_key_violation
+	"23502": {http.StatusConflict, "Not null violation"},                  // not_null_violation
+	"23514": {http.StatusConflict, "Check violation"},                     // check_violation
+	"25P02": {http.StatusConflict, "Transaction aborted"},                 // in_failed_sql_transaction
+	"08006": {http.StatusBadGateway, "Database connection failure"},       // connection_failure
+	"08001": {http.StatusBadGateway, "Unable to connect to database"},     // sqlclient_unable_to_establish_sqlconnection
+	"XX000": {http.StatusInternalServerError, "Internal database error"},  // internal_error
+	"22003": {http.StatusBadRequest, "Numeric value out of range"},        // numeric_value_out_of_range
+	"22007": {http.StatusBadRequest, "Invalid datetime format"},           // invalid_datetime_format
+	"42804": {http.StatusBadRequest, "Datatype mismatch"},                 // datatype_mismatch
+	"42702": {http.StatusBadRequest, "Ambiguous column"},                  // ambiguous_column
+	"25P03": {http.StatusGatewayTimeout, "Idle in transaction timeout"},   // idle_in_transaction_session_timeout
+}
+
+// sqlStateClassErrors maps PostgreSQL SQLSTATE error classes, the first two characters of the code,
+// to HTTP errors for the codes not found in sqlStateErrors.
+var sqlStateClassErrors = map[string]sqlStateError{
+	"08": {http.StatusBadGateway, "Database connection exception"},         // connection_exception
+	"0A": {http.StatusNotImplemented, "Feature not supported"},             // feature_not_supported
+	"22": {http.StatusBadRequest, "Data exception"},                        // data_exception
+	"23": {http.StatusConflict, "Integrity constraint violation"},          // integrity_constraint_violation
+	"25": {http.StatusConflict, "Invalid transaction state"},               // invalid_transaction_state
+	"28": {http.StatusBadGateway, "Database authentication failed"},        // invalid_authorization_specification
+	"40": {http.StatusConflict, "Transaction rollback"},                    // transaction_rollback
+	"42": {http.StatusBadRequest, "Syntax error or access rule violation"}, // syntax_error_or_access_rule_violation
+	"53": {http.StatusServiceUnavailable, "Insufficient resources"},        // insufficient_resources
+	"54": {http.StatusRequestEntityTooLarge, "Program limit exceeded"},     // program_limit_exceeded
+	"55": {http.StatusConflict, "Object not in prerequisite state"},        // object_not_in_prerequisite_state
+	"57": {http.StatusServiceUnavailable, "Operator intervention"},         // operator_intervention
+	"58": {http.StatusInternalServerError, "System error"},                 // system_error
+	"XX": {http.StatusInternalServerError, "Internal database error"},      // internal_error
+}
+
+// NewPostgresAPIError creates a new APIError for the given PostgreSQL error.
+// The HTTP status code and message are looked up by SQLSTATE code, then by SQLSTATE class,
+// and default to 400 Bad Request with the given fallback message.
+// The SQLSTATE code, severity, position, hint, column and constraint of the error are included.
+func NewPostgresAPIError(pgErr *pgconn.PgError, fallbackMessage string) *APIError {
+	mapped, ok := sqlStateErrors[pgErr.Code]
+	if !ok && len(pgErr.Code) == 5 {
+		mapped, ok = sqlStateClassErrors[pgErr.Code[:2]]
+	}
+	if !ok {
+		mapped = sqlStateError{http.StatusBadRequest, fallbackMessage}
+	}
+
+	details := pgErr.Error()
+	apiErr := NewAPIError(mapped.statusCode, mapped.message, &details)
+	apiErr.Code = pgErr.Code
+	apiErr.Severity = pgErr.Severity
+	apiErr.Position = pgErr.Position
+	apiErr.Hint = pgErr.Hint
+	apiErr.Column = pgErr.ColumnName
+	apiErr.Constraint = pgErr.ConstraintName
+
+	return apiErr
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestNewPostgresAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "insufficient privilege", code: "42501", wantStatus: http.StatusForbidden, wantMessage: "Permission denied"},
+		{name: "read-only transaction", code: "25006", wantStatus: http.StatusForbidden, wantMessage: "Read-only violation"},
+		{name: "undefined table", code: "42P01", wantStatus: http.StatusNotFound, wantMessage: "Table not found"},
+		{name: "undefined function", code: "42883", wantStatus: http.StatusNotFound, wantMessage: "Function not found"},
+		{name: "query canceled", code: "57014", wantStatus: http.StatusGatewayTimeout, wantMessage: "Query canceled"},
+		{name: "too many connections", code: "53300", wantStatus: http.StatusServiceUnavailable, wantMessage: "Too many connections"},
+		{name: "serialization failure", code: "40001", wantStatus: http.StatusConflict, wantMessage: "Serialization failure"},
+		{name: "unique violation", code: "23505", wantStatus: http.StatusConflict, wantMessage: "Unique violation"},
+		{name: "syntax error", code: "42601", wantStatus: http.StatusBadRequest, wantMessage: "Syntax error"},
+		{name: "invalid password", code: "28P01", wantStatus: http.StatusBadGateway, wantMessage: "Database authentication failed"},
+		{name: "class data exception", code: "22023", wantStatus: http.StatusBadRequest, wantMessage: "Data exception"},
+		{name: "class insufficient resources", code: "53200", wantStatus: http.StatusServiceUnavailable, wantMessage: "Insufficient resources"},
+		{name: "class integrity constraint", code: "23P01", wantStatus: http.StatusConflict, wantMessage: "Integrity constraint violation"},
+		{name: "class connection exception", code: "08003", wantStatus: http.StatusBadGateway, wantMessage: "Database connection exception"},
+		{name: "unknown code", code: "HV000", wantStatus: http.StatusBadRequest, wantMessage: "Error executing query"},
+		{name: "unknown class of short code", code: "42", wantStatus: http.StatusBadRequest, wantMessage: "Error executing query"},
+		{name: "no code", code: "", wantStatus: http.StatusBadRequest, wantMessage: "Error executing query"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pgErr := &pgconn.PgError{Code: test.code, Severity: "ERROR", Message: "failed"}
+			got := NewPostgresAPIError(pgErr, "Error executing query")
+
+			if got.StatusCode != test.wantStatus || got.StatusText != http.StatusText(test.wantStatus) || got.Message != test.wantMessage {
+				t.Errorf("NewPostgresAPIError(%q) = %d %q %q, want %d %q", test.code, got.StatusCode, got.StatusText, got.Message, test.wantStatus, test.wantMessage)
+			}
+			if got.Code != test.code {
+				t.Errorf("Code = %q, want %q", got.Code, test.code)
+			}
+		})
+	}
+}
+
+func TestNewPostgresAPIErrorFields(t *testing.T) {
+	pgErr := &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        `duplicate key value violates unique constraint "weather_pkey"`,
+		Hint:           "Use ON CONFLICT",
+		Position:       15,
+		ColumnName:     "id",
+		ConstraintName: "weather_pkey",
+	}
+
+	got := NewPostgresAPIError(pgErr, "Error executing query")
+
+	want := APIError{
+		StatusCode: http.StatusConflict,
+		StatusText: http.StatusText(http.StatusConflict),
+		Message:    "Unique violation",
+		Code:       "23505",
+		Severity:   "ERROR",
+		Position:   15,
+		Hint:       "Use ON CONFLICT",
+		Column:     "id",
+		Constraint: "weather_pkey",
+	}
+	if got.Details == nil || *got.Details != pgErr.Error() {
+		t.Errorf("Details = %v, want %q", got.Details, pgErr.Error())
+	}
+	got.Details = nil
+	if *got != want {
+		t.Errorf("NewPostgresAPIError = %+v, want %+v", *got, want)
+	}
+}
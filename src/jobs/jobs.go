@@ -0,0 +1,377 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/settings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"    // Waiting for a free job slot
+	StatusRunning   Status = "running"   // The query is running and the result is being spooled
+	StatusCompleted Status = "completed" // The result is available for download
+	StatusFailed    Status = "failed"    // The query or spooling failed, see the error of the job
+	StatusCanceled  Status = "canceled"  // The job was canceled by the client
+)
+
+const resultExtension = ".arrow" // Extension of the spooled result files
+
+// resultFilePattern matches the names of the (temporary) result files of jobs, which are named after the job id.
+var resultFilePattern = regexp.MustCompile(`^[0-9a-f]{32}` + regexp.QuoteMeta(resultExtension) + `(\.tmp)?$`)
+
+var (
+	jobs            = make(map[string]*Job) // Map to store the jobs by id
+	jobsMutex       sync.Mutex              // Mutex to ensure thread safety for jobs
+	jobsConfig      settings.JobsConfig     // The configuration set by Init
+	jobSlots        chan struct{}           // Semaphore limiting the number of running jobs
+	cleanupInterval = 1 * time.Minute       // Interval to check for expired jobs
+)
+
+// RunFunc executes the query of a job and writes the result to the writer,
+// reporting the progress on the job. The query must be canceled when the context is done.
+type RunFunc func(ctx context.Context, job *Job, w io.Writer) error
+
+// Job is a query executed in the background of which the result is spooled to disk.
+type Job struct {
+	ID         string
+	Connection string // The connection the query is executed on
+	ClientID   string // The user who submitted the job, empty for public connections
+
+	mutex       sync.Mutex
+	status      Status
+	rows        int64
+	bytes       int64
+	truncated   string
+	err         *errors.APIError
+	submittedAt time.Time
+	startedAt   time.Time
+	finishedAt  time.Time
+	cancel      context.CancelFunc
+}
+
+// Info is the status and progress of a job.
+type Info struct {
+	ID          string           `json:"id"`
+	Connection  string           `json:"connection"`
+	Status      Status           `json:"status"`
+	Rows        int64            `json:"rows"`                // The number of rows spooled so far
+	Bytes       int64            `json:"bytes"`               // The size of the spooled result so far
+	Truncated   string           `json:"truncated,omitempty"` // The limit which truncated the result, if any
+	Error       *errors.APIError `json:"error,omitempty"`
+	SubmittedAt time.Time        `json:"submittedAt"`
+	StartedAt   *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time       `json:"finishedAt,omitempty"`
+	ExpiresAt   *time.Time       `json:"expiresAt,omitempty"` // When the job and its result are removed
+	ElapsedMs   int64            `json:"elapsedMs"`           // The run time of the job
+}
+
+// Init sets the configuration of the jobs, creates the spool directory and removes the results
+// left by a previous run. Other files in the directory are kept, as it can be shared.
+// It starts a goroutine to periodically remove the expired jobs.
+func Init(config settings.JobsConfig) error {
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return fmt.Errorf("error creating jobs directory: %w", err)
+	}
+
+	files, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return fmt.Errorf("error reading jobs directory: %w", err)
+	}
+	for _, file := range files {
+		if !file.IsDir() && resultFilePattern.MatchString(file.Name()) {
+			os.Remove(filepath.Join(config.Dir, file.Name()))
+		}
+	}
+
+	jobsConfig = config
+	jobSlots = make(chan struct{}, config.MaxConcurrentJobs)
+
+	go periodicCleanup()
+
+	return nil
+}
+
+// periodicCleanup is a goroutine that periodically removes the jobs which finished longer than the TTL ago.
+func periodicCleanup() {
+	for {
+		time.Sleep(cleanupInterval)
+
+		jobsMutex.Lock()
+		for id, job := range jobs {
+			if job.expired() {
+				delete(jobs, id)
+				os.Remove(job.resultPath())
+				log.Debugf("Removed expired job: %s", id)
+			}
+		}
+		jobsMutex.Unlock()
+	}
+}
+
+// Submit creates a job executing run in the background and returns it.
+// The job waits for a free slot when the maximum number of concurrent jobs is running.
+func Submit(connection string, clientID string, run RunFunc) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		details := err.Error()
+		return nil, errors.NewAPIError(http.StatusInternalServerError, "Error creating job", &details)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(jobsConfig.TimeoutSeconds)*time.Second)
+	job := &Job{
+		ID:          id,
+		Connection:  connection,
+		ClientID:    clientID,
+		status:      StatusQueued,
+		submittedAt: time.Now(),
+		cancel:      cancel,
+	}
+
+	jobsMutex.Lock()
+	jobs[id] = job
+	jobsMutex.Unlock()
+
+	go job.execute(ctx, run)
+
+	return job, nil
+}
+
+// Get returns the job with the given id.
+func Get(id string) (*Job, bool) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+
+	job, ok := jobs[id]
+	return job, ok
+}
+
+// Delete removes the job with the given id and its result, a queued or running job is canceled.
+// It returns false when the job is not found.
+func Delete(id string) bool {
+	jobsMutex.Lock()
+	job, ok := jobs[id]
+	delete(jobs, id)
+	jobsMutex.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	// A job still running removes its result when it finishes
+	switch job.status {
+	case StatusQueued, StatusRunning:
+		job.status = StatusCanceled
+		job.cancel()
+	case StatusCompleted:
+		os.Remove(job.resultPath())
+	}
+
+	return true
+}
+
+// execute waits for a free job slot, runs the job and spools the result to the result file.
+func (j *Job) execute(ctx context.Context, run RunFunc) {
+	defer j.cancel()
+
+	select {
+	case jobSlots <- struct{}{}:
+		defer func() { <-jobSlots }()
+	case <-ctx.Done():
+		j.finish(ctx.Err())
+		return
+	}
+
+	j.mutex.Lock()
+	if j.status == StatusQueued {
+		j.status = StatusRunning
+		j.startedAt = time.Now()
+	}
+	j.mutex.Unlock()
+
+	j.finish(j.spool(ctx, run))
+}
+
+// spool runs the job writing the result to a temporary file, which is renamed to the result file when the job succeeds.
+func (j *Job) spool(ctx context.Context, run RunFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tmpPath := j.resultPath() + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		details := err.Error()
+		return errors.NewAPIError(http.StatusInternalServerError, "Error creating job result file", &details)
+	}
+
+	bw := bufio.NewWriterSize(file, 64*1024)
+	err = run(ctx, j, bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, j.resultPath())
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+		if _, ok := err.(*errors.APIError); !ok {
+			details := err.Error()
+			err = errors.NewAPIError(http.StatusInternalServerError, "Error writing job result", &details)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// finish sets the final status of the job based on the error returned by the run.
+// The result of a job canceled while running is removed.
+func (j *Job) finish(err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.finishedAt = time.Now()
+
+	switch {
+	case j.status == StatusCanceled:
+		os.Remove(j.resultPath())
+	case err != nil:
+		j.status = StatusFailed
+		j.err = jobError(err)
+		log.Errorf("Job '%s' on connection '%s' failed: %v", j.ID, j.Connection, err)
+	default:
+		j.status = StatusCompleted
+	}
+}
+
+// jobError returns the error of a failed job as APIError, so it is reported as such in the job status.
+func jobError(err error) *errors.APIError {
+	if apiErr, ok := err.(*errors.APIError); ok {
+		return apiErr
+	}
+
+	if err == context.DeadlineExceeded {
+		return errors.NewAPIError(http.StatusGatewayTimeout, "Job timed out", nil)
+	}
+
+	details := err.Error()
+	return errors.NewAPIError(http.StatusInternalServerError, "Job failed", &details)
+}
+
+// AddProgress adds the number of rows and bytes written to the result of the job.
+func (j *Job) AddProgress(rows int64, bytes int64) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.rows += rows
+	j.bytes += bytes
+}
+
+// SetTruncated marks the result of the job as truncated by the limit with the given reason.
+func (j *Job) SetTruncated(reason string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.truncated = reason
+}
+
+// Info returns a snapshot of the status and progress of the job.
+func (j *Job) Info() Info {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	info := Info{
+		ID:          j.ID,
+		Connection:  j.Connection,
+		Status:      j.status,
+		Rows:        j.rows,
+		Bytes:       j.bytes,
+		Truncated:   j.truncated,
+		Error:       j.err,
+		SubmittedAt: j.submittedAt,
+	}
+
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		info.StartedAt = &startedAt
+		info.ElapsedMs = time.Since(startedAt).Milliseconds()
+	}
+
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		expiresAt := finishedAt.Add(time.Duration(jobsConfig.TTLSeconds) * time.Second)
+		info.FinishedAt = &finishedAt
+		info.ExpiresAt = &expiresAt
+		if !j.startedAt.IsZero() {
+			info.ElapsedMs = finishedAt.Sub(j.startedAt).Milliseconds()
+		}
+	}
+
+	return info
+}
+
+// OpenResult opens the spooled result of the job for reading.
+// It returns an APIError when the job is not completed.
+func (j *Job) OpenResult() (*os.File, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.status != StatusCompleted {
+		details := fmt.Sprintf("The job is %s", j.status)
+		return nil, errors.NewAPIError(http.StatusConflict, "Job result not available", &details)
+	}
+
+	file, err := os.Open(j.resultPath())
+	if err != nil {
+		details := err.Error()
+		return nil, errors.NewAPIError(http.StatusGone, "Job result not available", &details)
+	}
+
+	return file, nil
+}
+
+// expired returns true if the job finished longer than the TTL ago.
+func (j *Job) expired() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return !j.finishedAt.IsZero() && time.Since(j.finishedAt) > time.Duration(jobsConfig.TTLSeconds)*time.Second
+}
+
+// resultPath returns the path of the spooled result of the job.
+func (j *Job) resultPath() string {
+	return filepath.Join(jobsConfig.Dir, j.ID+resultExtension)
+}
+
+// newJobID returns a random 128 bit job id.
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
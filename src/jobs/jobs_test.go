@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+func TestJobError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{name: "API error", err: errors.NewAPIError(http.StatusForbidden, "Permission denied", nil), wantStatus: http.StatusForbidden, wantError: "Permission denied"},
+		{name: "timeout", err: context.DeadlineExceeded, wantStatus: http.StatusGatewayTimeout, wantError: "Job timed out"},
+		{name: "other error", err: fmt.Errorf("disk full"), wantStatus: http.StatusInternalServerError, wantError: "Job failed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(Info{Error: jobError(test.err)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var info struct {
+				Error struct {
+					Status int    `json:"status"`
+					Error  string `json:"error"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(data, &info); err != nil {
+				t.Fatal(err)
+			}
+			if info.Error.Status != test.wantStatus || info.Error.Error != test.wantError {
+				t.Errorf("job error = %s, want status %d and error %q", data, test.wantStatus, test.wantError)
+			}
+		})
+	}
+}
+
+func TestInitRemovesOnlyJobResults(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]bool{
+		"0123456789abcdef0123456789abcdef.arrow":     false,
+		"0123456789abcdef0123456789abcdef.arrow.tmp": false,
+		"export.arrow": true,
+		"0123456789abcdef0123456789abcdef.arrow.bak": true,
+		"0123456789ABCDEF0123456789ABCDEF.arrow":     true,
+		"notes.txt":                                  true,
+	}
+	for name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Init(settings.JobsConfig{Dir: dir, MaxConcurrentJobs: 1}); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	for name, kept := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s exists = %v, want %v", name, exists, kept)
+		}
+	}
+}
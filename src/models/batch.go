@@ -0,0 +1,90 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const MaxBatchStatements = 100 // Maximum number of statements of a batch request
+
+// BatchRequestBody represents the structure of the incoming JSON payload of a batch request,
+// the statements are executed in order in one transaction.
+type BatchRequestBody struct {
+	Statements []BatchStatement `json:"statements"`
+}
+
+// BatchStatement is a statement of a batch request with its own params and result format.
+type BatchStatement struct {
+	Query      string        `json:"query"`
+	Params     []interface{} `json:"params,omitempty"`
+	ParamTypes []string      `json:"paramTypes,omitempty"`
+	Format     FormatType    `json:"format,omitempty"`
+	FormatOptions
+
+	args []interface{} // Validated params to bind as $1..$n
+}
+
+// Args returns the validated statement params to bind as $1..$n when executing the statement.
+func (s *BatchStatement) Args() []interface{} {
+	return s.args
+}
+
+// UnmarshalJSON unmarshals the JSON data into the BatchRequestBody struct.
+// It returns an error if there are no statements or more than MaxBatchStatements.
+func (rb *BatchRequestBody) UnmarshalJSON(data []byte) error {
+	// Create a secondary type to avoid recursion
+	type Alias BatchRequestBody
+	aux := &struct {
+		*Alias
+	}{
+		Alias: (*Alias)(rb),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if len(rb.Statements) == 0 || len(rb.Statements) > MaxBatchStatements {
+		return fmt.Errorf("a batch must have between 1 and %d statements", MaxBatchStatements)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON unmarshals the JSON data into the BatchStatement struct.
+// The results of a batch are returned in one JSON document, so only the JSON based formats are supported.
+// Params are decoded and converted like the params of the query endpoint.
+func (s *BatchStatement) UnmarshalJSON(data []byte) error {
+	// Create a secondary type to avoid recursion
+	type Alias BatchStatement
+	aux := &struct {
+		*Alias
+	}{
+		Alias: (*Alias)(s),
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&aux); err != nil {
+		return err
+	}
+
+	if s.Query == "" {
+		return fmt.Errorf("statement query is empty")
+	}
+
+	if s.Format == "" {
+		s.Format = JSONFormat
+	} else if s.Format != JSONFormat && s.Format != JSONDataArrayFormat && s.Format != GeoJSONFormat {
+		return fmt.Errorf("invalid statement format '%s', supported formats in a batch: 'json', 'jsonDataArray', 'geojson'", s.Format)
+	}
+
+	args, err := ValidateParams(s.Params, s.ParamTypes)
+	if err != nil {
+		return err
+	}
+	s.args = args
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// NamedQueryRequestBody represents the structure of the incoming JSON payload
+// when executing a named query from the query catalog of a connection.
+type NamedQueryRequestBody struct {
+	Params []interface{} `json:"params,omitempty"`
+	Format FormatType    `json:"format,omitempty"`
+	FormatOptions
+	PagingOptions
+	Transaction string `json:"transaction,omitempty"` // The id of the open transaction to execute the query in
+}
+
+// UnmarshalJSON unmarshals the JSON data into the NamedQueryRequestBody struct.
+// It sets the default value for the Format field if it is empty and validates it.
+// Params are decoded with json.Number, they are validated against the param types
+// of the named query when it is executed.
+func (rb *NamedQueryRequestBody) UnmarshalJSON(data []byte) error {
+	// Create a secondary type to avoid recursion
+	type Alias NamedQueryRequestBody
+	aux := &struct {
+		*Alias
+	}{
+		Alias: (*Alias)(rb),
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&aux); err != nil {
+		return err
+	}
+
+	if err := validateFormat(&rb.Format); err != nil {
+		return err
+	}
+
+	if err := validateFormatOptions(&rb.FormatOptions); err != nil {
+		return err
+	}
+
+	return validatePagingOptions(&rb.PagingOptions, rb.Transaction)
+}
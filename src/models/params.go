@@ -0,0 +1,278 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxQueryParams is the maximum number of bind parameters supported by the PostgreSQL wire protocol.
+const maxQueryParams = math.MaxUint16
+
+// paramConverters maps the supported PostgreSQL type hints to the function converting
+// a JSON decoded value into a Go value pgx can bind to a parameter of that type.
+var paramConverters = map[string]func(value interface{}) (interface{}, error){
+	"text":             toText,
+	"varchar":          toText,
+	"char":             toText,
+	"name":             toText,
+	"int2":             toInt(16),
+	"smallint":         toInt(16),
+	"int4":             toInt(32),
+	"integer":          toInt(32),
+	"int":              toInt(32),
+	"int8":             toInt(64),
+	"bigint":           toInt(64),
+	"float4":           toFloat(32),
+	"real":             toFloat(32),
+	"float8":           toFloat(64),
+	"double precision": toFloat(64),
+	"numeric":          toNumeric,
+	"decimal":          toNumeric,
+	"bool":             toBool,
+	"boolean":          toBool,
+	"date":             toTime("2006-01-02"),
+	"timestamp":        toTime(time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"),
+	"timestamptz":      toTime(time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00"),
+	"uuid":             toUUID,
+	"json":             toJSON,
+	"jsonb":            toJSON,
+	"bytea":            toBytea,
+}
+
+// ValidateParams checks the query parameters and their optional type hints and converts
+// them into the arguments that are bound as $1..$n when executing the query.
+func ValidateParams(params []interface{}, types []string) ([]interface{}, error) {
+	if len(params) > maxQueryParams {
+		return nil, fmt.Errorf("too many params, a maximum of %d is supported", maxQueryParams)
+	}
+
+	if len(types) > 0 && len(types) != len(params) {
+		return nil, fmt.Errorf("paramTypes must contain a type for each of the %d params, got %d", len(params), len(types))
+	}
+
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		var err error
+		if len(types) == 0 || types[i] == "" {
+			args[i], err = normalizeParam(param)
+		} else {
+			args[i], err = convertParam(param, types[i])
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for param $%d: %v", i+1, err)
+		}
+	}
+
+	return args, nil
+}
+
+// convertParam converts a JSON decoded value to the Go value matching the given PostgreSQL type hint.
+// Array hints, such as 'int8[]', require a JSON array and convert each of its elements.
+func convertParam(value interface{}, pgType string) (interface{}, error) {
+	pgType = strings.ToLower(strings.TrimSpace(pgType))
+
+	if elementType, isArray := strings.CutSuffix(pgType, "[]"); isArray {
+		if value == nil {
+			return nil, nil
+		}
+
+		elements, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array for type '%s'", pgType)
+		}
+
+		converted := make([]interface{}, len(elements))
+		for i, element := range elements {
+			v, err := convertParam(element, elementType)
+			if err != nil {
+				return nil, err
+			}
+			converted[i] = v
+		}
+		return converted, nil
+	}
+
+	converter, ok := paramConverters[pgType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported param type '%s'", pgType)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	return converter(value)
+}
+
+// normalizeParam converts a JSON decoded value without type hint into a value pgx can bind.
+// JSON numbers are converted to int64 when they are integral and to float64 otherwise.
+func normalizeParam(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, element := range v {
+			n, err := normalizeParam(element)
+			if err != nil {
+				return nil, err
+			}
+			normalized[i] = n
+		}
+		return normalized, nil
+	case map[string]interface{}:
+		return toJSON(v)
+	default:
+		return v, nil
+	}
+}
+
+func toText(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return nil, fmt.Errorf("expected a string, got %T", value)
+	}
+}
+
+func toInt(bitSize int) func(value interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		var s string
+		switch v := value.(type) {
+		case json.Number:
+			s = v.String()
+		case string:
+			s = v
+		default:
+			return nil, fmt.Errorf("expected an integer, got %T", value)
+		}
+
+		i, err := strconv.ParseInt(s, 10, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid %d bit integer", s, bitSize)
+		}
+		return i, nil
+	}
+}
+
+func toFloat(bitSize int) func(value interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		var s string
+		switch v := value.(type) {
+		case json.Number:
+			s = v.String()
+		case string:
+			s = v
+		default:
+			return nil, fmt.Errorf("expected a number, got %T", value)
+		}
+
+		f, err := strconv.ParseFloat(s, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid %d bit float", s, bitSize)
+		}
+		if bitSize == 32 {
+			return float32(f), nil
+		}
+		return f, nil
+	}
+}
+
+func toNumeric(value interface{}) (interface{}, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("expected a number, got %T", value)
+	}
+
+	var numeric pgtype.Numeric
+	if err := numeric.Scan(s); err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid numeric", s)
+	}
+	return numeric, nil
+}
+
+func toBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid boolean", v)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("expected a boolean, got %T", value)
+	}
+}
+
+func toTime(layouts ...string) func(value interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a date/time string, got %T", value)
+		}
+
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' is not a valid date/time, expected format '%s'", s, layouts[0])
+	}
+}
+
+func toUUID(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a uuid string, got %T", value)
+	}
+
+	var uuid pgtype.UUID
+	if err := uuid.Scan(s); err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid uuid", s)
+	}
+	return uuid, nil
+}
+
+func toJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(data), nil
+}
+
+func toBytea(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a base64 encoded string, got %T", value)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("expected a base64 encoded string: %v", err)
+	}
+	return data, nil
+}
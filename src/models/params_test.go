@@ -0,0 +1,141 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// decodeParams decodes the JSON params like the request body, with numbers as json.Number.
+func decodeParams(t *testing.T, data string) []interface{} {
+	t.Helper()
+
+	var params []interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&params); err != nil {
+		t.Fatalf("decoding params %s: %v", data, err)
+	}
+	return params
+}
+
+// numeric returns the pgx numeric of the unscaled value * 10^exp.
+func numeric(t *testing.T, unscaled string, exp int32) pgtype.Numeric {
+	t.Helper()
+
+	i, ok := new(big.Int).SetString(unscaled, 10)
+	if !ok {
+		t.Fatalf("invalid integer %s", unscaled)
+	}
+	return pgtype.Numeric{Int: i, Exp: exp, Valid: true}
+}
+
+func TestValidateParams(t *testing.T) {
+	uuid := pgtype.UUID{Bytes: [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}, Valid: true}
+
+	tests := []struct {
+		name    string
+		params  string
+		types   []string
+		want    []interface{}
+		wantErr string
+	}{
+		{
+			name:   "without types",
+			params: `[1, 1.5, "a", true, null, [1, 2.5], {"a": 1}]`,
+			want:   []interface{}{int64(1), 1.5, "a", true, nil, []interface{}{int64(1), 2.5}, json.RawMessage(`{"a":1}`)},
+		},
+		{name: "large integer without type", params: `[9007199254740993]`, want: []interface{}{int64(9007199254740993)}},
+		{name: "no params", params: `[]`, types: []string{}, want: []interface{}{}},
+		{name: "empty type hint", params: `[1, "2"]`, types: []string{"", "int4"}, want: []interface{}{int64(1), int64(2)}},
+		{name: "type hint case and spaces", params: `[1]`, types: []string{" INT8 "}, want: []interface{}{int64(1)}},
+		{name: "fewer types than params", params: `[1, 2]`, types: []string{"int4"}, wantErr: "paramTypes must contain a type for each of the 2 params, got 1"},
+		{name: "more types than params", params: `[1]`, types: []string{"int4", "int4"}, wantErr: "paramTypes must contain a type for each of the 1 params, got 2"},
+		{name: "unsupported type", params: `[1]`, types: []string{"geometry"}, wantErr: "invalid value for param $1: unsupported param type 'geometry'"},
+		{name: "null", params: `[null, null]`, types: []string{"int4", "text"}, want: []interface{}{nil, nil}},
+
+		{name: "int2", params: `[32767]`, types: []string{"int2"}, want: []interface{}{int64(32767)}},
+		{name: "int2 overflow", params: `[32768]`, types: []string{"smallint"}, wantErr: "invalid value for param $1: '32768' is not a valid 16 bit integer"},
+		{name: "int4 from string", params: `["-42"]`, types: []string{"integer"}, want: []interface{}{int64(-42)}},
+		{name: "int4 overflow", params: `[2147483648]`, types: []string{"int4"}, wantErr: "invalid value for param $1: '2147483648' is not a valid 32 bit integer"},
+		{name: "int8 max", params: `[9223372036854775807]`, types: []string{"int8"}, want: []interface{}{int64(9223372036854775807)}},
+		{name: "int8 overflow", params: `[9223372036854775808]`, types: []string{"bigint"}, wantErr: "invalid value for param $1: '9223372036854775808' is not a valid 64 bit integer"},
+		{name: "int with fraction", params: `[1, 1.5]`, types: []string{"int4", "int8"}, wantErr: "invalid value for param $2: '1.5' is not a valid 64 bit integer"},
+		{name: "int from boolean", params: `[true]`, types: []string{"int4"}, wantErr: "invalid value for param $1: expected an integer, got bool"},
+
+		{name: "float4", params: `[1.5]`, types: []string{"float4"}, want: []interface{}{float32(1.5)}},
+		{name: "float8", params: `["0.1"]`, types: []string{"double precision"}, want: []interface{}{0.1}},
+		{name: "float from text", params: `["abc"]`, types: []string{"float8"}, wantErr: "invalid value for param $1: 'abc' is not a valid 64 bit float"},
+
+		{
+			name:   "numeric keeps precision",
+			params: `[12345678901234567890.123456789, "-0.10"]`,
+			types:  []string{"numeric", "decimal"},
+			want:   []interface{}{numeric(t, "12345678901234567890123456789", -9), numeric(t, "-10", -2)},
+		},
+		{name: "numeric from boolean", params: `[false]`, types: []string{"numeric"}, wantErr: "invalid value for param $1: expected a number, got bool"},
+		{name: "numeric from text", params: `["1,5"]`, types: []string{"numeric"}, wantErr: "invalid value for param $1: '1,5' is not a valid numeric"},
+
+		{name: "text", params: `["a", 42, true]`, types: []string{"text", "varchar", "text"}, want: []interface{}{"a", "42", "true"}},
+		{name: "text from array", params: `[["a"]]`, types: []string{"text"}, wantErr: "invalid value for param $1: expected a string, got []interface {}"},
+		{name: "bool", params: `[true, "false"]`, types: []string{"bool", "boolean"}, want: []interface{}{true, false}},
+		{name: "bool from number", params: `[1]`, types: []string{"bool"}, wantErr: "invalid value for param $1: expected a boolean, got json.Number"},
+
+		{name: "date", params: `["2024-01-31"]`, types: []string{"date"}, want: []interface{}{time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}},
+		{name: "invalid date", params: `["31-01-2024"]`, types: []string{"date"}, wantErr: "invalid value for param $1: '31-01-2024' is not a valid date/time, expected format '2006-01-02'"},
+		{
+			name:   "timestamp",
+			params: `["2024-01-31T10:00:00.5", "2024-01-31 10:00:00"]`,
+			types:  []string{"timestamp", "timestamp"},
+			want:   []interface{}{time.Date(2024, 1, 31, 10, 0, 0, 500000000, time.UTC), time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)},
+		},
+		{name: "timestamptz", params: `["2024-01-31T10:00:00Z"]`, types: []string{"timestamptz"}, want: []interface{}{time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)}},
+		{name: "timestamp from number", params: `[1706695200]`, types: []string{"timestamptz"}, wantErr: "invalid value for param $1: expected a date/time string, got json.Number"},
+
+		{name: "uuid", params: `["123e4567-e89b-12d3-a456-426614174000"]`, types: []string{"uuid"}, want: []interface{}{uuid}},
+		{name: "invalid uuid", params: `["123"]`, types: []string{"uuid"}, wantErr: "invalid value for param $1: '123' is not a valid uuid"},
+		{name: "json", params: `[{"a": [1, 2]}, "b"]`, types: []string{"jsonb", "json"}, want: []interface{}{json.RawMessage(`{"a":[1,2]}`), json.RawMessage(`"b"`)}},
+		{name: "bytea", params: `["AQID"]`, types: []string{"bytea"}, want: []interface{}{[]byte{1, 2, 3}}},
+		{name: "invalid bytea", params: `["AQI*"]`, types: []string{"bytea"}, wantErr: "invalid value for param $1: expected a base64 encoded string: illegal base64 data at input byte 3"},
+
+		{name: "array", params: `[[1, "2", null]]`, types: []string{"int8[]"}, want: []interface{}{[]interface{}{int64(1), int64(2), nil}}},
+		{name: "null array", params: `[null]`, types: []string{"text[]"}, want: []interface{}{nil}},
+		{name: "array element overflow", params: `[[1, 32768]]`, types: []string{"int2[]"}, wantErr: "invalid value for param $1: '32768' is not a valid 16 bit integer"},
+		{name: "array from scalar", params: `[1]`, types: []string{"int8[]"}, wantErr: "invalid value for param $1: expected an array for type 'int8[]'"},
+		{name: "array of unsupported type", params: `[[1]]`, types: []string{"point[]"}, wantErr: "invalid value for param $1: unsupported param type 'point'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ValidateParams(decodeParams(t, test.params), test.types)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("ValidateParams error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateParams returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ValidateParams = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateParamsTooMany(t *testing.T) {
+	params := make([]interface{}, maxQueryParams+1)
+	if _, err := ValidateParams(params, nil); err == nil {
+		t.Errorf("ValidateParams of %d params returned no error", len(params))
+	}
+
+	if _, err := ValidateParams(params[:maxQueryParams], nil); err != nil {
+		t.Errorf("ValidateParams of %d params returned error: %v", maxQueryParams, err)
+	}
+}
@@ -1,15 +1,30 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 )
 
 // QueryRequestBody represents the structure of the incoming JSON payload
 type QueryRequestBody struct {
-	Connection string     `json:"connection"`
-	Query      string     `json:"query"`
-	Format     FormatType `json:"format,omitempty"`
+	Connection string        `json:"connection"`
+	Query      string        `json:"query"`
+	Params     []interface{} `json:"params,omitempty"`
+	ParamTypes []string      `json:"paramTypes,omitempty"`
+	Format     FormatType    `json:"format,omitempty"`
+	FormatOptions
+	PagingOptions
+	Transaction string `json:"transaction,omitempty"` // The id of the open transaction to execute the query in
+
+	args []interface{} // Validated params to bind as $1..$n
+}
+
+// Args returns the validated query params to bind as $1..$n when executing the query.
+func (rb *QueryRequestBody) Args() []interface{} {
+	return rb.args
 }
 
 type FormatType string
@@ -20,11 +35,64 @@ const (
 	ArrowFormat         FormatType = "arrow"
 	ParquetFormat       FormatType = "parquet"
 	CSVFormat           FormatType = "csv"
+	NDJSONFormat        FormatType = "ndjson"
+	GeoJSONFormat       FormatType = "geojson"
+)
+
+// ParquetCompression is the compression codec of the Parquet format.
+type ParquetCompression string
+
+const (
+	ParquetSnappy       ParquetCompression = "snappy"
+	ParquetZstd         ParquetCompression = "zstd"
+	ParquetGzip         ParquetCompression = "gzip"
+	ParquetLz4          ParquetCompression = "lz4"
+	ParquetUncompressed ParquetCompression = "none"
 )
 
+const (
+	DefaultParquetRowGroupSize = 64 * 1024 // Rows per Parquet row group
+	MaxParquetRowGroupSize     = 1024 * 1024
+)
+
+// FormatOptions contains the optional settings of the output formats.
+type FormatOptions struct {
+	GeometryColumn string             `json:"geometryColumn,omitempty"` // geojson: the column to use as feature geometry
+	IDColumn       string             `json:"idColumn,omitempty"`       // geojson: the column to use as feature id
+	Compression    ParquetCompression `json:"compression,omitempty"`    // parquet: the compression codec, default snappy
+	RowGroupSize   int                `json:"rowGroupSize,omitempty"`   // parquet: the number of rows per row group
+}
+
+// PagingOptions contains the settings to fetch a query result in pages using a server-side cursor.
+type PagingOptions struct {
+	PageSize int    `json:"pageSize,omitempty"` // Open a cursor and return the first page of pageSize rows
+	Cursor   string `json:"cursor,omitempty"`   // The continuation token of the cursor to return the next page of
+}
+
+// IsPaged returns true if the request opens a cursor or fetches the next page of a cursor.
+func (o PagingOptions) IsPaged() bool {
+	return o.PageSize > 0 || o.Cursor != ""
+}
+
+// validatePagingOptions returns an error if the page size is negative
+// or the query is paged inside an open transaction, which is not supported.
+func validatePagingOptions(options *PagingOptions, transaction string) error {
+	if options.PageSize < 0 {
+		return fmt.Errorf("invalid pageSize %d, must be positive", options.PageSize)
+	}
+
+	if transaction != "" && options.IsPaged() {
+		return fmt.Errorf("paging is not supported inside a transaction")
+	}
+
+	return nil
+}
+
 // UnmarshalJSON unmarshals the JSON data into the QueryRequestBody struct.
 // It sets default values for Connections and Format fields if they are empty.
 // It also validates the Format field and returns an error if it is not a supported format.
+// Params are decoded with json.Number to keep the precision of large integers and are
+// converted to their bind arguments using the optional ParamTypes hints.
 func (rb *QueryRequestBody) UnmarshalJSON(data []byte) error {
 	// Create a secondary type to avoid recursion
 	type Alias QueryRequestBody
@@ -35,7 +103,9 @@ func (rb *QueryRequestBody) UnmarshalJSON(data []byte) error {
 	}
 
 	// Unmarshal the data into the auxiliary struct
-	if err := json.Unmarshal(data, &aux); err != nil {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&aux); err != nil {
 		return err
 	}
 
@@ -44,16 +114,88 @@ func (rb *QueryRequestBody) UnmarshalJSON(data []byte) error {
 		rb.Connection = "default"
 	}
 
-	// Set the default value if Format is empty
-	if rb.Format == "" {
-		rb.Format = JSONFormat
-	} else if !isValidFormat(rb.Format) {
-		return fmt.Errorf("invalid format type '%s', supported formats: 'json', 'jsonDataArray'", rb.Format)
+	if err := validateFormat(&rb.Format); err != nil {
+		return err
+	}
+
+	if err := validateFormatOptions(&rb.FormatOptions); err != nil {
+		return err
+	}
+
+	if err := validatePagingOptions(&rb.PagingOptions, rb.Transaction); err != nil {
+		return err
+	}
+
+	args, err := ValidateParams(rb.Params, rb.ParamTypes)
+	if err != nil {
+		return err
+	}
+	rb.args = args
+
+	return nil
+}
+
+// validateFormat sets the format to the default JSON format if it is empty
+// and returns an error if it is not a supported format.
+func validateFormat(format *FormatType) error {
+	if *format == "" {
+		*format = JSONFormat
+	} else if !isValidFormat(*format) {
+		return fmt.Errorf("invalid format type '%s', supported formats: 'json', 'jsonDataArray', 'ndjson', 'geojson', 'csv', 'arrow', 'parquet'", *format)
 	}
 
 	return nil
 }
 
+// validateFormatOptions sets the default Parquet options
+// and returns an error if the compression or row group size is not supported.
+func validateFormatOptions(options *FormatOptions) error {
+	switch options.Compression {
+	case "":
+		options.Compression = ParquetSnappy
+	case ParquetSnappy, ParquetZstd, ParquetGzip, ParquetLz4, ParquetUncompressed:
+	default:
+		return fmt.Errorf("invalid compression '%s', supported compressions: 'snappy', 'zstd', 'gzip', 'lz4', 'none'", options.Compression)
+	}
+
+	if options.RowGroupSize == 0 {
+		options.RowGroupSize = DefaultParquetRowGroupSize
+	} else if options.RowGroupSize < 0 || options.RowGroupSize > MaxParquetRowGroupSize {
+		return fmt.Errorf("invalid rowGroupSize %d, must be between 1 and %d", options.RowGroupSize, MaxParquetRowGroupSize)
+	}
+
+	return nil
+}
+
+// ParseFormatQuery returns the format and format options set in the query string of a request,
+// e.g. "?format=parquet&compression=zstd", validated and defaulted like the format of a request body.
+func ParseFormatQuery(query url.Values) (FormatType, FormatOptions, error) {
+	format := FormatType(query.Get("format"))
+	options := FormatOptions{
+		GeometryColumn: query.Get("geometryColumn"),
+		IDColumn:       query.Get("idColumn"),
+		Compression:    ParquetCompression(query.Get("compression")),
+	}
+
+	if rowGroupSize := query.Get("rowGroupSize"); rowGroupSize != "" {
+		size, err := strconv.Atoi(rowGroupSize)
+		if err != nil {
+			return "", options, fmt.Errorf("invalid rowGroupSize '%s'", rowGroupSize)
+		}
+		options.RowGroupSize = size
+	}
+
+	if err := validateFormat(&format); err != nil {
+		return "", options, err
+	}
+
+	if err := validateFormatOptions(&options); err != nil {
+		return "", options, err
+	}
+
+	return format, options, nil
+}
+
 func isValidFormat(format FormatType) bool {
-	return format == JSONFormat || format == JSONDataArrayFormat || format == ArrowFormat || format == CSVFormat || format == ParquetFormat
+	return format == JSONFormat || format == JSONDataArrayFormat || format == ArrowFormat || format == CSVFormat || format == ParquetFormat || format == NDJSONFormat || format == GeoJSONFormat
 }
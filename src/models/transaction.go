@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// IsolationLevel is the transaction isolation level of an interactive transaction.
+type IsolationLevel string
+
+const (
+	ReadCommitted  IsolationLevel = "readCommitted"
+	RepeatableRead IsolationLevel = "repeatableRead"
+	Serializable   IsolationLevel = "serializable"
+)
+
+// TransactionRequestBody represents the structure of the incoming JSON payload when beginning a transaction.
+type TransactionRequestBody struct {
+	IsolationLevel IsolationLevel `json:"isolationLevel,omitempty"` // Default readCommitted
+	ReadOnly       bool           `json:"readOnly,omitempty"`
+}
+
+// UnmarshalJSON unmarshals the JSON data into the TransactionRequestBody struct.
+// It sets the default isolation level if it is empty and validates it.
+func (rb *TransactionRequestBody) UnmarshalJSON(data []byte) error {
+	// Create a secondary type to avoid recursion
+	type Alias TransactionRequestBody
+	aux := &struct {
+		*Alias
+	}{
+		Alias: (*Alias)(rb),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	switch rb.IsolationLevel {
+	case "":
+		rb.IsolationLevel = ReadCommitted
+	case ReadCommitted, RepeatableRead, Serializable:
+	default:
+		return fmt.Errorf("invalid isolationLevel '%s', supported isolation levels: 'readCommitted', 'repeatableRead', 'serializable'", rb.IsolationLevel)
+	}
+
+	return nil
+}
@@ -6,29 +6,57 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"syscall"
 	"time"
 
+	"github.com/apache/arrow/go/v18/arrow/flight"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
+	"github.com/sogelink-research/pgrest/api/flightsql"
 	"github.com/sogelink-research/pgrest/api/handlers"
 	"github.com/sogelink-research/pgrest/api/middleware"
+	"github.com/sogelink-research/pgrest/cache"
 	"github.com/sogelink-research/pgrest/database"
+	"github.com/sogelink-research/pgrest/jobs"
 	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/transactions"
 )
 
 // Start starts the PGRest server with the given configuration.
 // It initializes the necessary resources, sets up the main handler,
 // and listens for incoming HTTP requests on the specified port.
 func Start(config settings.Config) {
+	if err := jobs.Init(config.PGRest.Jobs); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cache.Init(config.PGRest.Cache); err != nil {
+		log.Fatal(err)
+	}
+
 	router := createRouter(config)
 	server := &http.Server{Addr: fmt.Sprintf(":%v", config.PGRest.Port), Handler: router}
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
+	flightServer := startFlightSQLServer(config)
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			log.Info("Reload signal received, reloading configuration...")
+			reloadConfig()
+		}
+	}()
+
+	if config.PGRest.WatchConfig {
+		go settings.WatchConfig(5*time.Second, reloadConfig)
+	}
 
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		<-sig
 
@@ -44,11 +72,18 @@ func Start(config settings.Config) {
 			}
 		}()
 
+		if flightServer != nil {
+			flightServer.Shutdown()
+		}
+
 		err := server.Shutdown(shutdownCtx)
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		// Roll back the open cursors and transactions, so closing the pools does not wait for their connections
+		transactions.CloseAll()
+
 		log.Info("Server stopped successfully")
 		serverStopCtx()
 	}()
@@ -65,6 +100,60 @@ func Start(config settings.Config) {
 	<-serverCtx.Done()
 }
 
+// reloadConfig reloads the configuration file and swaps the users, connections and CORS settings
+// used by new requests. In-flight requests finish with the configuration they started with.
+// The pools of removed connections and connections with a changed connection string are closed,
+// the current configuration is kept when the new configuration is invalid.
+func reloadConfig() {
+	previous, current, err := settings.ReloadConfig()
+	if err != nil {
+		log.Errorf("Failed to reload configuration, keeping current configuration: %v", err)
+		return
+	}
+
+	for _, conn := range previous.Connections {
+		updated, err := current.GetConnectionConfig(conn.Name)
+		if err != nil || updated.ConnectionString != conn.ConnectionString {
+			database.ClosePool(conn.Name)
+		}
+	}
+
+	log.SetLevel(current.PGRest.GetLogLevel())
+
+	// Only the CORS and log level settings of the server are applied without a restart
+	previousServer, currentServer := previous.PGRest, current.PGRest
+	previousServer.CORS, currentServer.CORS = settings.CorsConfig{}, settings.CorsConfig{}
+	previousServer.Debug, currentServer.Debug = false, false
+	previousServer.LogLevel, currentServer.LogLevel = "", ""
+	if !reflect.DeepEqual(previousServer, currentServer) {
+		log.Warn("Changed pgrest settings other than cors, debug and logLevel require a restart to take effect")
+	}
+
+	log.Info("Configuration reloaded")
+}
+
+// startFlightSQLServer starts the Arrow Flight SQL server when it is enabled in the configuration.
+// It returns nil when the Flight SQL server is disabled.
+func startFlightSQLServer(config settings.Config) flight.Server {
+	if !config.PGRest.FlightSQL.Enabled {
+		return nil
+	}
+
+	flightServer, err := flightsql.NewFlightServer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go func() {
+		if err := flightServer.Serve(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	log.Info(fmt.Sprintf("Flight SQL server started, running on port %v", config.PGRest.FlightSQL.Port))
+	return flightServer
+}
+
 // createRouter creates and configures the router for the server.
 // It sets up the necessary middleware and routes for handling API requests.
 // The router is configured with the provided `config` settings.
@@ -72,21 +161,77 @@ func createRouter(config settings.Config) http.Handler {
 	router := chi.NewRouter()
 	router.Use(middleware.Logger("router", log.StandardLogger(), logrus.DebugLevel))
 	router.Use(chimiddleware.Recoverer)
-	router.Use(chimiddleware.Throttle(config.PGRest.MaxConcurrentRequests))
-	router.Use(chimiddleware.Timeout(time.Duration(config.PGRest.Timeout) * time.Second))
 
 	router.NotFound(handlers.NotFoundHandler)
-	router.Route("/api/{connection}/query", func(r chi.Router) {
-		r.Use(middleware.CORSMiddleware(config.PGRest.CORS))
-		r.Use(middleware.AuthMiddleware(config))
-		r.Post("/", handlers.QueryHandler(config))
+
+	// Job results are already spooled, so downloads are not throttled and can take longer than the request timeout
+	router.Route("/api/jobs/{id}/result", func(r chi.Router) {
+		r.Use(middleware.CORSMiddleware())
+		r.Use(middleware.JobAuthMiddleware())
+		r.Get("/", handlers.JobResultHandler())
 	})
 
-	startTime := time.Now()
-	router.Route("/api/status", func(r chi.Router) {
-		r.Use(middleware.CORSMiddleware(config.PGRest.CORS))
-		r.Use(chimiddleware.NoCache)
-		r.Get("/", handlers.StatusHandler(startTime))
+	router.Group(func(router chi.Router) {
+		router.Use(chimiddleware.Throttle(config.PGRest.MaxConcurrentRequests))
+		router.Use(chimiddleware.Timeout(time.Duration(config.PGRest.Timeout) * time.Second))
+
+		router.Route("/api/{connection}/query", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(middleware.AuthMiddleware())
+			r.Post("/", handlers.QueryHandler())
+		})
+
+		router.Route("/api/{connection}/queries/{name}", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(middleware.AuthMiddleware())
+			r.Post("/", handlers.NamedQueryHandler())
+		})
+
+		router.Route("/api/{connection}/tiles/{layer}/{z}/{x}/{y}.mvt", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(middleware.AuthMiddleware())
+			r.Get("/", handlers.TileHandler())
+		})
+
+		router.Route("/api/{connection}/batch", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(middleware.AuthMiddleware())
+			r.Post("/", handlers.BatchHandler())
+		})
+
+		router.Route("/api/{connection}/transactions", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(middleware.AuthMiddleware())
+			r.Post("/", handlers.TransactionBeginHandler())
+			r.Post("/{transaction}/commit", handlers.TransactionCommitHandler())
+			r.Post("/{transaction}/rollback", handlers.TransactionRollbackHandler())
+		})
+
+		router.Route("/api/{connection}/jobs", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(middleware.AuthMiddleware())
+			r.Post("/", handlers.JobSubmitHandler())
+		})
+
+		router.Route("/api/{connection}/cursors/{cursor}", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(middleware.AuthMiddleware())
+			r.Delete("/", handlers.CursorCloseHandler())
+		})
+
+		router.Route("/api/jobs/{id}", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(middleware.JobAuthMiddleware())
+			r.Get("/", handlers.JobStatusHandler())
+			r.Delete("/", handlers.JobCancelHandler())
+		})
+
+		startTime := time.Now()
+		router.Route("/api/status", func(r chi.Router) {
+			r.Use(middleware.CORSMiddleware())
+			r.Use(chimiddleware.NoCache)
+			r.Get("/", handlers.StatusHandler(startTime))
+		})
 	})
 
 	return router
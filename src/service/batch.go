@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sogelink-research/pgrest/database"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+// BatchStatement is a statement of a batch with the args bound to its placeholders $1..$n.
+type BatchStatement struct {
+	Query string
+	Args  []interface{}
+}
+
+// QueryBatch executes the statements in order in one repeatable read transaction, so all statements see
+// the same snapshot of the database. The statements are sent in one round-trip using a pgx batch.
+// The result of each statement is passed to handle in order. When a statement or handle fails the
+// transaction is rolled back, otherwise it is committed.
+// It returns an APIError with the number of the failed statement if any.
+func QueryBatch(ctx context.Context, statements []BatchStatement, connection *settings.ConnectionConfig, options QueryOptions, handle func(i int, rows pgx.Rows, columns []string) error) error {
+	pool, err := database.GetDBPool(connection.Name, connection.ConnectionString)
+	if err != nil {
+		return connectionError(err, connection)
+	}
+
+	if options.ReadOnly {
+		for i, statement := range statements {
+			if countStatements(statement.Query) > 1 {
+				apiErr := errors.NewAPIError(http.StatusBadRequest, "Multiple statements are not allowed on a read-only connection", nil)
+				apiErr.Statement = i + 1
+				return apiErr
+			}
+		}
+	}
+
+	txOptions := pgx.TxOptions{IsoLevel: pgx.RepeatableRead}
+	if options.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	tx, err := pool.BeginTx(ctx, txOptions)
+	if err != nil {
+		return connectionError(err, connection)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	names, values := options.sessionSettings()
+	if len(names) > 0 {
+		batch.Queue(setSessionSettingsQuery, names, values)
+	}
+	for _, statement := range statements {
+		batch.Queue(statement.Query, statement.Args...)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	if len(names) > 0 {
+		if _, err := results.Exec(); err != nil {
+			return QueryError(err)
+		}
+	}
+
+	for i := range statements {
+		if err := handleBatchResult(results, i, handle); err != nil {
+			return batchStatementError(err, i)
+		}
+	}
+
+	if err := results.Close(); err != nil {
+		return QueryError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return QueryError(err)
+	}
+
+	return nil
+}
+
+// handleBatchResult reads the result of the next statement of the batch and passes it to handle.
+func handleBatchResult(results pgx.BatchResults, i int, handle func(i int, rows pgx.Rows, columns []string) error) error {
+	rows, err := results.Query()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Fetch the first row so errors raised before the first row are returned before handle is called
+	rows, err = prefetchRows(rows)
+	if err != nil {
+		return err
+	}
+
+	if err := handle(i, rows, getColumnNames(rows.FieldDescriptions())); err != nil {
+		return err
+	}
+
+	rows.Close()
+	return rows.Err()
+}
+
+// batchStatementError converts the error of the statement with index i of a batch to an APIError with the statement number.
+func batchStatementError(err error, i int) error {
+	apiErr, ok := err.(*errors.APIError)
+	if !ok {
+		apiErr = QueryError(err).(*errors.APIError)
+	}
+
+	apiErr.Statement = i + 1
+	return apiErr
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/transactions"
+)
+
+const cursorName = "pgrest_cursor" // Name of the cursor, a transaction holds one cursor
+
+// OpenCursor begins a transaction on a connection acquired from the pool and declares a cursor for the query,
+// the result is fetched in pages of pageSize rows using FetchCursorPage. The args are bound to the query placeholders $1..$n.
+// The session settings of the options are applied local to the transaction.
+// The returned transaction is acquired by the caller, which must release it when done.
+// It returns an APIError when the query is invalid or the user has too many open cursors.
+func OpenCursor(ctx context.Context, query string, args []interface{}, connection *settings.ConnectionConfig, options QueryOptions, clientID string, pageSize int, limits transactions.Limits) (*transactions.Transaction, error) {
+	if countStatements(query) > 1 {
+		return nil, errors.NewAPIError(http.StatusBadRequest, "Multiple statements are not allowed in a paged query", nil)
+	}
+
+	txOptions := pgx.TxOptions{}
+	if options.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	t, err := beginHeldTransaction(ctx, connection, txOptions, options, transactions.KindCursor, clientID, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = t.Tx.Exec(ctx, fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", cursorName, query), args...)
+	if err != nil {
+		t.Close(ctx, false)
+		t.Release()
+		return nil, QueryError(err)
+	}
+
+	t.Cursor = &transactions.Cursor{Name: cursorName, PageSize: pageSize}
+	return t, nil
+}
+
+// FetchCursorPage fetches the next page of at most pageSize rows of the cursor of the transaction, pageSize
+// is at most the page size of the cursor. One row is fetched ahead and kept for the next page to report
+// whether there is a next page, so the rows of the page are buffered in memory. The transaction must be acquired by the caller.
+// It returns the rows of the page, the column names, whether there is a next page and an APIError if any.
+func FetchCursorPage(ctx context.Context, t *transactions.Transaction, pageSize int) (pgx.Rows, []string, bool, error) {
+	cursor := t.Cursor
+
+	values := cursor.Pending
+	if count := pageSize + 1 - len(values); count > 0 {
+		rows, err := t.Tx.Query(ctx, fmt.Sprintf("FETCH FORWARD %d FROM %s", count, cursor.Name))
+		if err != nil {
+			return nil, nil, false, QueryError(err)
+		}
+		defer rows.Close()
+
+		if cursor.Fields == nil {
+			cursor.Fields = append([]pgconn.FieldDescription(nil), rows.FieldDescriptions()...)
+		}
+
+		for rows.Next() {
+			row, err := rows.Values()
+			if err != nil {
+				return nil, nil, false, QueryError(err)
+			}
+			values = append(values, row)
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, nil, false, QueryError(err)
+		}
+	}
+
+	cursor.Pending = nil
+	hasNext := len(values) > pageSize
+	if hasNext {
+		cursor.Pending = values[pageSize:]
+		values = values[:pageSize]
+	}
+	cursor.Returned += len(values)
+
+	page := &bufferedRows{fields: cursor.Fields, values: values, index: -1}
+	return page, getColumnNames(cursor.Fields), hasNext, nil
+}
+
+// bufferedRows are the rows of a cursor page buffered in memory.
+type bufferedRows struct {
+	fields []pgconn.FieldDescription
+	values [][]interface{}
+	index  int
+}
+
+func (r *bufferedRows) Close() {}
+
+func (r *bufferedRows) Err() error {
+	return nil
+}
+
+func (r *bufferedRows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag(fmt.Sprintf("FETCH %d", len(r.values)))
+}
+
+func (r *bufferedRows) FieldDescriptions() []pgconn.FieldDescription {
+	return r.fields
+}
+
+func (r *bufferedRows) Next() bool {
+	if r.index+1 >= len(r.values) {
+		return false
+	}
+	r.index++
+	return true
+}
+
+func (r *bufferedRows) Scan(dest ...interface{}) error {
+	return fmt.Errorf("scan is not supported on a cursor page")
+}
+
+func (r *bufferedRows) Values() ([]interface{}, error) {
+	return r.values[r.index], nil
+}
+
+func (r *bufferedRows) RawValues() [][]byte {
+	return nil
+}
+
+func (r *bufferedRows) Conn() *pgx.Conn {
+	return nil
+}
@@ -2,29 +2,140 @@ package service
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sogelink-research/pgrest/database"
 	"github.com/sogelink-research/pgrest/errors"
 	"github.com/sogelink-research/pgrest/settings"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// QueryOptions contains the options applied when executing a query.
+type QueryOptions struct {
+	StatementTimeoutMs int            // PostgreSQL statement_timeout in milliseconds, 0 for no timeout
+	ReadOnly           bool           // Run the query inside a read-only transaction
+	Claims             *SessionClaims // Role and claims of the user for row-level security, nil to not set them
+}
+
+// SessionClaims are the role and claims of a user, set local to the transaction of a query so
+// PostgreSQL row-level security policies can use them, e.g. current_setting('pgrest.client_id').
+type SessionClaims struct {
+	Role     string            // The role to switch to, like SET LOCAL ROLE, empty to keep the role of the connection
+	ClientID string            // Set as pgrest.client_id, empty for public connections
+	Claims   map[string]string // Set as pgrest.claims.<name>
+}
+
+// NewQueryOptions returns the options to execute a query on the connection for the optional user with the limits.
+// The role and claims of the user are set when the connection has row-level security enabled.
+func NewQueryOptions(connection *settings.ConnectionConfig, user *settings.UserConfig, limits settings.LimitsConfig) QueryOptions {
+	options := QueryOptions{
+		StatementTimeoutMs: limits.StatementTimeoutMs,
+		ReadOnly:           connection.ReadOnly,
+	}
+
+	if connection.RowLevelSecurity {
+		options.Claims = &SessionClaims{}
+		if user != nil {
+			options.Claims.Role = user.Role
+			options.Claims.ClientID = user.ClientID
+			options.Claims.Claims = user.Claims
+		}
+	}
+
+	return options
+}
+
+// needsTransaction returns true if the options require the query to run inside a transaction.
+func (o QueryOptions) needsTransaction() bool {
+	return o.StatementTimeoutMs > 0 || o.ReadOnly || o.Claims != nil
+}
+
+// sessionSettings returns the names and values of the settings to apply local to the transaction of a query.
+func (o QueryOptions) sessionSettings() ([]string, []string) {
+	var names, values []string
+	if o.StatementTimeoutMs > 0 {
+		names = append(names, "statement_timeout")
+		values = append(values, strconv.Itoa(o.StatementTimeoutMs))
+	}
+
+	if o.Claims != nil {
+		if o.Claims.Role != "" {
+			names = append(names, "role")
+			values = append(values, o.Claims.Role)
+		}
+
+		names = append(names, "pgrest.client_id")
+		values = append(values, o.Claims.ClientID)
+
+		claimNames := make([]string, 0, len(o.Claims.Claims))
+		for name := range o.Claims.Claims {
+			claimNames = append(claimNames, name)
+		}
+		sort.Strings(claimNames)
+
+		for _, name := range claimNames {
+			names = append(names, "pgrest.claims."+name)
+			values = append(values, o.Claims.Claims[name])
+		}
+	}
+
+	return names, values
+}
+
+// setSessionSettingsQuery sets the settings $1 to the values $2 local to the transaction in one statement.
+const setSessionSettingsQuery = "SELECT set_config(name, value, true) FROM unnest($1::text[], $2::text[]) AS settings(name, value)"
+
+// applySessionSettings applies the session settings of the options local to the transaction.
+func applySessionSettings(ctx context.Context, tx pgx.Tx, options QueryOptions) error {
+	names, values := options.sessionSettings()
+	if len(names) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, setSessionSettingsQuery, names, values)
+	return err
+}
+
 // QueryPostgres executes a query on a PostgreSQL database using the provided connection configuration.
+// The args are bound to the query placeholders $1..$n. The query is canceled on the
+// database when the given context is done, e.g. when the client disconnects or the request times out.
+// When the options require session settings the query runs inside a transaction which
+// is ended when the returned rows are closed.
 // It returns the result rows, column names, and an API error if any.
-func QueryPostgres(query string, connection *settings.ConnectionConfig) (pgx.Rows, []string, error) {
+func QueryPostgres(ctx context.Context, query string, args []interface{}, connection *settings.ConnectionConfig, options QueryOptions) (pgx.Rows, []string, error) {
 	pool, err := database.GetDBPool(connection.Name, connection.ConnectionString)
 	if err != nil {
-		return nil, nil, errors.NewAPIError(http.StatusInternalServerError, fmt.Sprintf("Error connecting to database: %v", connection.Name), nil)
+		return nil, nil, connectionError(err, connection)
 	}
 
-	rows, err := pool.Query(context.Background(), query)
+	if options.ReadOnly && countStatements(query) > 1 {
+		return nil, nil, errors.NewAPIError(http.StatusBadRequest, "Multiple statements are not allowed on a read-only connection", nil)
+	}
+
+	var rows pgx.Rows
+	if options.needsTransaction() {
+		rows, err = queryInTransaction(ctx, pool, query, args, options)
+	} else {
+		rows, err = pool.Query(ctx, query, args...)
+	}
+
+	if err != nil {
+		return nil, nil, QueryError(err)
+	}
 
+	// Fetch the first row so errors raised before the first row are returned
+	// before the response is started
+	rows, err = prefetchRows(rows)
 	if err != nil {
-		errorMessage := fmt.Sprintf("%v", err.Error())
-		return nil, nil, errors.NewAPIError(http.StatusBadRequest, "Error executing query", &errorMessage)
+		return nil, nil, QueryError(err)
 	}
 
 	columns := getColumnNames(rows.FieldDescriptions())
@@ -32,6 +143,153 @@ func QueryPostgres(query string, connection *settings.ConnectionConfig) (pgx.Row
 	return rows, columns, nil
 }
 
+// DescribeQuery returns the result columns of the query without executing it, using an unnamed prepared statement.
+// When the options have session settings the query is described inside a transaction in which they are applied,
+// so the role of the user is used to resolve the tables. The transaction is rolled back.
+// It returns an APIError when the query is invalid, e.g. because of a syntax error or an unknown table.
+func DescribeQuery(ctx context.Context, query string, connection *settings.ConnectionConfig, options QueryOptions) ([]pgconn.FieldDescription, error) {
+	pool, err := database.GetDBPool(connection.Name, connection.ConnectionString)
+	if err != nil {
+		return nil, connectionError(err, connection)
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, connectionError(err, connection)
+	}
+	defer conn.Release()
+
+	if options.needsTransaction() {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return nil, connectionError(err, connection)
+		}
+		defer tx.Rollback(ctx)
+
+		if err := applySessionSettings(ctx, tx, options); err != nil {
+			return nil, QueryError(err)
+		}
+	}
+
+	description, err := conn.Conn().PgConn().Prepare(ctx, "", query, nil)
+	if err != nil {
+		return nil, QueryError(err)
+	}
+
+	return description.Fields, nil
+}
+
+// queryInTransaction begins a transaction, applies the session settings of the options
+// local to the transaction and executes the query.
+// The transaction is committed or rolled back when the returned rows are closed.
+func queryInTransaction(ctx context.Context, pool *pgxpool.Pool, query string, args []interface{}, options QueryOptions) (pgx.Rows, error) {
+	txOptions := pgx.TxOptions{}
+	if options.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	tx, err := pool.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applySessionSettings(ctx, tx, options); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return &txRows{Rows: rows, ctx: ctx, tx: tx}, nil
+}
+
+// txRows wraps the rows of a query executed inside a transaction,
+// closing the rows ends the transaction.
+type txRows struct {
+	pgx.Rows
+	ctx context.Context
+	tx  pgx.Tx
+}
+
+// Close closes the rows and commits the transaction, or rolls it back when reading the rows failed.
+func (r *txRows) Close() {
+	r.Rows.Close()
+
+	if r.Rows.Err() != nil {
+		r.tx.Rollback(r.ctx)
+		return
+	}
+
+	r.tx.Commit(r.ctx)
+}
+
+// prefetchedRows wraps rows of which the first row is already fetched.
+type prefetchedRows struct {
+	pgx.Rows
+	prefetched bool // The first row is fetched but not yet returned by Next
+}
+
+// Next returns the prefetched row first and advances the wrapped rows afterwards.
+func (r *prefetchedRows) Next() bool {
+	if r.prefetched {
+		r.prefetched = false
+		return true
+	}
+	return r.Rows.Next()
+}
+
+// prefetchRows fetches the first row of the rows, returning the error when fetching failed.
+func prefetchRows(rows pgx.Rows) (pgx.Rows, error) {
+	hasRow := rows.Next()
+	if !hasRow {
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+	}
+
+	return &prefetchedRows{Rows: rows, prefetched: hasRow}, nil
+}
+
+// connectionError converts an error returned when connecting to the database to an APIError.
+// PostgreSQL errors, e.g. authentication failures, are mapped based on their SQLSTATE code,
+// invalid connection strings are server errors and other errors mean the database is unreachable.
+func connectionError(err error, connection *settings.ConnectionConfig) error {
+	message := fmt.Sprintf("Error connecting to database: %v", connection.Name)
+	log.Errorf("%s: %v", message, err)
+
+	var parseErr *pgconn.ParseConfigError
+	if stderrors.As(err, &parseErr) {
+		return errors.NewAPIError(http.StatusInternalServerError, message, nil)
+	}
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) {
+		apiErr := errors.NewPostgresAPIError(pgErr, message)
+		apiErr.Details = &message
+		return apiErr
+	}
+
+	return errors.NewAPIError(http.StatusServiceUnavailable, message, nil)
+}
+
+// QueryError converts an error returned when executing a query to an APIError.
+// PostgreSQL errors are mapped to an HTTP status code based on their SQLSTATE code.
+func QueryError(err error) error {
+	errorMessage := fmt.Sprintf("%v", err.Error())
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) {
+		return errors.NewPostgresAPIError(pgErr, "Error executing query")
+	}
+
+	return errors.NewAPIError(http.StatusBadRequest, "Error executing query", &errorMessage)
+}
+
 // getColumnNames returns a slice of column names extracted from the given pgconn.FieldDescription slice.
 func getColumnNames(columns []pgconn.FieldDescription) []string {
 	names := make([]string, len(columns))
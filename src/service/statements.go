@@ -0,0 +1,119 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// countStatements returns the number of non-empty SQL statements in the query.
+// Semicolons inside string literals, quoted identifiers, dollar quoted strings
+// and comments are not considered statement separators.
+func countStatements(query string) int {
+	count := 0
+	hasContent := false
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ';':
+			if hasContent {
+				count++
+			}
+			hasContent = false
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end
+			}
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipBlockComment(query, i)
+			continue
+		case c == '\'':
+			// E'...' is an escape string, unless the E is the end of a keyword or identifier, e.g. LIKE'...'
+			escapes := i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') && (i < 2 || !isIdentifierChar(query[i-2]))
+			i = skipQuoted(query, i, '\'', escapes)
+		case c == '"':
+			i = skipQuoted(query, i, '"', false)
+		case c == '$' && (i == 0 || !isIdentifierChar(query[i-1])):
+			// A $ inside an identifier, e.g. a$b, does not start a dollar quote
+			i = skipDollarQuoted(query, i)
+		case unicode.IsSpace(rune(c)):
+			continue
+		}
+		hasContent = true
+	}
+
+	if hasContent {
+		count++
+	}
+
+	return count
+}
+
+// isIdentifierChar returns true if the character can be part of an unquoted identifier or keyword.
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// skipBlockComment returns the index of the last character of the (nested) block comment starting at start.
+func skipBlockComment(query string, start int) int {
+	depth := 0
+	for i := start; i < len(query)-1; i++ {
+		if query[i] == '/' && query[i+1] == '*' {
+			depth++
+			i++
+		} else if query[i] == '*' && query[i+1] == '/' {
+			depth--
+			i++
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(query)
+}
+
+// skipQuoted returns the index of the closing quote of the quoted string starting at start.
+// A doubled quote is an escaped quote, backslash escapes are only allowed when escapes is true.
+func skipQuoted(query string, start int, quote byte, escapes bool) int {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(query)
+}
+
+// skipDollarQuoted returns the index of the last character of the dollar quoted string starting at start,
+// e.g. $$...$$ or $tag$...$tag$. When the $ does not start a dollar quote, e.g. a $1 placeholder, start is returned.
+func skipDollarQuoted(query string, start int) int {
+	end := start + 1
+	for end < len(query) && (query[end] == '_' || unicode.IsLetter(rune(query[end])) || (end > start+1 && unicode.IsDigit(rune(query[end])))) {
+		end++
+	}
+
+	if end >= len(query) || query[end] != '$' {
+		return start
+	}
+
+	tag := query[start : end+1]
+	closing := strings.Index(query[end+1:], tag)
+	if closing < 0 {
+		return len(query)
+	}
+
+	return end + closing + len(tag)
+}
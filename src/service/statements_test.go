@@ -0,0 +1,55 @@
+package service
+
+import "testing"
+
+func TestCountStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "empty", query: "", want: 0},
+		{name: "whitespace and semicolons", query: " ;\n ; \t", want: 0},
+		{name: "single", query: "SELECT 1", want: 1},
+		{name: "trailing semicolon", query: "SELECT 1;", want: 1},
+		{name: "trailing semicolon and whitespace", query: "SELECT 1;\n  ", want: 1},
+		{name: "multiple", query: "SELECT 1; SELECT 2", want: 2},
+		{name: "empty statements", query: "SELECT 1;;; SELECT 2;", want: 2},
+		{name: "string literal", query: "SELECT 'a;b'", want: 1},
+		{name: "doubled quote", query: "SELECT 'it''s; fine'", want: 1},
+		{name: "doubled quote then statement", query: "SELECT ''''; SELECT 2", want: 2},
+		{name: "backslash in standard string", query: `SELECT 'a\'; SELECT 2`, want: 2},
+		{name: "escape string", query: `SELECT E'a\'; b'`, want: 1},
+		{name: "lowercase escape string", query: `SELECT e'a\\'; SELECT 2`, want: 2},
+		{name: "escape string with doubled quote", query: `SELECT E'a''\'; b'`, want: 1},
+		{name: "keyword ending with E before string", query: `SELECT * FROM t WHERE a LIKE'a\'; SELECT 2`, want: 2},
+		{name: "quoted identifier", query: `SELECT 1 AS "a;b"`, want: 1},
+		{name: "quoted identifier doubled quote", query: `SELECT 1 AS "a"";b"; SELECT 2`, want: 2},
+		{name: "dollar quoted", query: "SELECT $$a;b$$", want: 1},
+		{name: "dollar quoted with quotes", query: "SELECT $$it's; \"x$$; SELECT 2", want: 2},
+		{name: "tagged dollar quoted", query: "SELECT $fn$ a; $$ b; $fn$", want: 1},
+		{name: "tagged dollar quoted with digits", query: "SELECT $t1$;$t1$; SELECT 2", want: 2},
+		{name: "function body", query: "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql; SELECT f()", want: 2},
+		{name: "unterminated dollar quote", query: "SELECT $$a; SELECT 2", want: 1},
+		{name: "placeholders", query: "SELECT $1; SELECT $2", want: 2},
+		{name: "placeholder before dollar quote", query: "SELECT $1, $$;$$", want: 1},
+		{name: "dollar in identifier", query: "SELECT a$b$; SELECT 2", want: 2},
+		{name: "line comment", query: "SELECT 1 -- a; b\n", want: 1},
+		{name: "line comment then statement", query: "SELECT 1; -- a; b\nSELECT 2", want: 2},
+		{name: "only comments", query: "-- a;\n/* b; */", want: 0},
+		{name: "block comment", query: "SELECT /* ; */ 1", want: 1},
+		{name: "nested block comment", query: "SELECT /* a /* b; */ c; */ 1; SELECT 2", want: 2},
+		{name: "unterminated block comment", query: "SELECT 1 /* ; SELECT 2", want: 1},
+		{name: "comment markers in string", query: "SELECT '--', '/*'; SELECT 2", want: 2},
+		{name: "unterminated string", query: "SELECT 'a; SELECT 2", want: 1},
+		{name: "division and minus", query: "SELECT 4/2 - 1; SELECT 2", want: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := countStatements(test.query); got != test.want {
+				t.Errorf("countStatements(%q) = %d, want %d", test.query, got, test.want)
+			}
+		})
+	}
+}
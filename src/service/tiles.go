@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/utils"
+)
+
+// QueryTile returns the Mapbox Vector Tile of the layer for the tile z/x/y.
+// The layer table or query is wrapped using ST_TileEnvelope, ST_AsMVTGeom and ST_AsMVT.
+// It returns an empty tile when the tile contains no features.
+func QueryTile(ctx context.Context, connection *settings.ConnectionConfig, layer *settings.TileLayerConfig, z int, x int, y int, options QueryOptions) ([]byte, error) {
+	query := buildTileQuery(layer)
+	args := []interface{}{int32(z), int32(x), int32(y), layer.Name, layer.GeometryColumn}
+	if layer.IDColumn != "" {
+		args = append(args, layer.IDColumn)
+	}
+
+	rows, _, err := QueryPostgres(ctx, query, args, connection, options)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tile []byte
+	if rows.Next() {
+		if err := rows.Scan(&tile); err != nil {
+			return nil, QueryError(err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, QueryError(err)
+	}
+
+	return tile, nil
+}
+
+// buildTileQuery builds the query returning the vector tile of the layer, using the params
+// $1, $2, $3 for the tile z, x, y, $4 for the layer name, $5 for the geometry column and $6 for the id column.
+// Features are selected using the tile envelope expanded with the buffer, so features
+// crossing the tile edges are rendered correctly.
+func buildTileQuery(layer *settings.TileLayerConfig) string {
+	source := layer.SQL
+	if source == "" {
+		source = "SELECT * FROM " + quoteQualifiedIdentifier(layer.Table)
+	}
+
+	geometry := pgx.Identifier{layer.GeometryColumn}.Sanitize()
+
+	columns := ""
+	for _, column := range layer.Columns {
+		columns += ", t." + pgx.Identifier{column}.Sanitize()
+	}
+	if layer.IDColumn != "" && !utils.Contains(layer.Columns, layer.IDColumn) {
+		columns += ", t." + pgx.Identifier{layer.IDColumn}.Sanitize()
+	}
+
+	featureID := ""
+	if layer.IDColumn != "" {
+		featureID = ", $6"
+	}
+
+	return fmt.Sprintf(`WITH bounds AS (
+	SELECT ST_TileEnvelope($1, $2, $3) AS geom
+), filter AS (
+	SELECT ST_Transform(ST_Expand(geom, (ST_XMax(geom) - ST_XMin(geom)) * %[3]d / %[2]d.0), %[4]d) AS geom FROM bounds
+), mvtgeom AS (
+	SELECT ST_AsMVTGeom(ST_Transform(t.%[1]s, 3857), bounds.geom, %[2]d, %[3]d, true) AS %[1]s%[5]s
+	FROM (%[6]s) t, bounds, filter
+	WHERE t.%[1]s && filter.geom
+)
+SELECT ST_AsMVT(mvtgeom.*, $4, %[2]d, $5%[7]s) FROM mvtgeom`,
+		geometry, layer.Extent, layer.Buffer, layer.SRID, columns, source, featureID)
+}
+
+// quoteQualifiedIdentifier quotes a possibly schema qualified identifier, e.g. public.buildings.
+func quoteQualifiedIdentifier(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sogelink-research/pgrest/database"
+	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/settings"
+	"github.com/sogelink-research/pgrest/transactions"
+)
+
+// BeginTransaction begins an interactive transaction on a connection acquired from the pool, which is held open
+// across requests until it is committed or rolled back. The session settings of the options are applied local
+// to the transaction. The returned transaction is acquired by the caller, which must release it when done.
+// It returns an APIError when the user has too many open transactions.
+func BeginTransaction(ctx context.Context, connection *settings.ConnectionConfig, txOptions pgx.TxOptions, options QueryOptions, clientID string, limits transactions.Limits) (*transactions.Transaction, error) {
+	if options.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	return beginHeldTransaction(ctx, connection, txOptions, options, transactions.KindTransaction, clientID, limits)
+}
+
+// QueryTransaction executes a query inside the interactive transaction, the transaction must be acquired by the caller.
+// The args are bound to the query placeholders $1..$n. The rows must be closed before the transaction is released.
+// When the connection is closed, e.g. because the query was canceled, the transaction is rolled back.
+// It returns the result rows, column names, and an API error if any.
+func QueryTransaction(ctx context.Context, t *transactions.Transaction, query string, args []interface{}, options QueryOptions) (pgx.Rows, []string, error) {
+	if options.ReadOnly && countStatements(query) > 1 {
+		return nil, nil, errors.NewAPIError(http.StatusBadRequest, "Multiple statements are not allowed on a read-only connection", nil)
+	}
+
+	rows, err := t.Tx.Query(ctx, query, args...)
+	if err == nil {
+		rows, err = prefetchRows(rows)
+	}
+
+	if err != nil {
+		if t.Tx.Conn().IsClosed() {
+			t.Close(context.Background(), false)
+		}
+		return nil, nil, QueryError(err)
+	}
+
+	return rows, getColumnNames(rows.FieldDescriptions()), nil
+}
+
+// CommitTransaction commits the interactive transaction, the transaction must be acquired by the caller.
+// A transaction in which a statement failed is rolled back by PostgreSQL, which is returned as conflict.
+func CommitTransaction(ctx context.Context, t *transactions.Transaction) error {
+	err := t.Close(ctx, true)
+	if stderrors.Is(err, pgx.ErrTxCommitRollback) {
+		details := "A statement in the transaction failed, the transaction is rolled back"
+		return errors.NewAPIError(http.StatusConflict, "Transaction aborted", &details)
+	}
+
+	if err != nil {
+		return QueryError(err)
+	}
+
+	return nil
+}
+
+// beginHeldTransaction begins a transaction of the kind which is held open across requests and applies the
+// session settings of the options local to the transaction.
+func beginHeldTransaction(ctx context.Context, connection *settings.ConnectionConfig, txOptions pgx.TxOptions, options QueryOptions, kind transactions.Kind, clientID string, limits transactions.Limits) (*transactions.Transaction, error) {
+	pool, err := database.GetDBPool(connection.Name, connection.ConnectionString)
+	if err != nil {
+		return nil, connectionError(err, connection)
+	}
+
+	t, err := transactions.Begin(ctx, pool, txOptions, kind, connection.Name, clientID, limits)
+	if err != nil {
+		var apiErr *errors.APIError
+		if stderrors.As(err, &apiErr) {
+			return nil, err
+		}
+		return nil, connectionError(err, connection)
+	}
+
+	if err := applySessionSettings(ctx, t.Tx, options); err != nil {
+		t.Close(ctx, false)
+		t.Release()
+		return nil, QueryError(err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sogelink-research/pgrest/database"
+	"github.com/sogelink-research/pgrest/settings"
+)
+
+var (
+	geometryTypeOIDs      = make(map[string]map[uint32]bool) // Cached PostGIS geometry type OIDs per connection string
+	geometryTypeOIDsMutex sync.Mutex                         // Mutex to ensure thread safety for geometryTypeOIDs
+)
+
+// GetGeometryTypeOIDs returns the type OIDs of the PostGIS geometry and geography types of the connection.
+// PostGIS is an extension so the OIDs differ per database, they are looked up once per connection string,
+// so a connection of which the connection string changed on a reload looks them up again.
+// An empty set is returned when PostGIS is not installed.
+func GetGeometryTypeOIDs(ctx context.Context, connection *settings.ConnectionConfig) (map[uint32]bool, error) {
+	geometryTypeOIDsMutex.Lock()
+	defer geometryTypeOIDsMutex.Unlock()
+
+	if oids, ok := geometryTypeOIDs[connection.ConnectionString]; ok {
+		return oids, nil
+	}
+
+	pool, err := database.GetDBPool(connection.Name, connection.ConnectionString)
+	if err != nil {
+		return nil, connectionError(err, connection)
+	}
+
+	rows, err := pool.Query(ctx, "SELECT oid FROM pg_type WHERE typname IN ('geometry', 'geography')")
+	if err != nil {
+		return nil, QueryError(err)
+	}
+	defer rows.Close()
+
+	oids := make(map[uint32]bool)
+	for rows.Next() {
+		var oid uint32
+		if err := rows.Scan(&oid); err != nil {
+			return nil, QueryError(err)
+		}
+		oids[oid] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, QueryError(err)
+	}
+
+	geometryTypeOIDs[connection.ConnectionString] = oids
+	return oids, nil
+}
@@ -0,0 +1,115 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// parseDocument parses the content of the configuration file to a JSON document.
+// The format is chosen by the extension of the file: JSON with comments and trailing commas
+// (.json, .jsonc and .conf), YAML (.yaml and .yml) or TOML (.toml).
+func parseDocument(file string, content []byte) (map[string]interface{}, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json", ".jsonc", ".conf":
+		return parseJSON(content)
+	case ".yaml", ".yml":
+		var document interface{}
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			return nil, ValidationErrors{{Message: err.Error()}}
+		}
+		return normalizeDocument(document)
+	case ".toml":
+		var document map[string]interface{}
+		if err := toml.Unmarshal(content, &document); err != nil {
+			return nil, ValidationErrors{{Message: err.Error()}}
+		}
+		return normalizeDocument(document)
+	}
+
+	return nil, fmt.Errorf("unsupported configuration format '%s', use .json, .jsonc, .conf, .yaml, .yml or .toml", filepath.Ext(file))
+}
+
+// parseJSON parses JSON with comments and trailing commas to a JSON document,
+// numbers are kept as json.Number so large values don't lose precision.
+func parseJSON(content []byte) (map[string]interface{}, error) {
+	content = standardizeJSON(content)
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, parseError(err, content)
+	}
+	if decoder.More() {
+		line := 1 + bytes.Count(content[:decoder.InputOffset()], []byte("\n"))
+		return nil, ValidationErrors{{Message: fmt.Sprintf("line %d: unexpected content after the configuration", line)}}
+	}
+
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, ValidationErrors{{Message: "the configuration must be a JSON object"}}
+	}
+	return object, nil
+}
+
+// standardizeJSON replaces the // and /* */ comments and the trailing commas before a closing
+// bracket or brace outside of strings by spaces, so offsets and line numbers of errors are kept.
+func standardizeJSON(content []byte) []byte {
+	result := bytes.Clone(content)
+	comma := -1 // The position of the last comma followed by whitespace only
+	for i := 0; i < len(result); i++ {
+		switch c := result[i]; {
+		case c == '"':
+			comma = -1
+			for i++; i < len(result) && result[i] != '"'; i++ {
+				if result[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(result) && result[i+1] == '/':
+			for ; i < len(result) && result[i] != '\n'; i++ {
+				result[i] = ' '
+			}
+		case c == '/' && i+1 < len(result) && result[i+1] == '*':
+			end := bytes.Index(result[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(result)
+			} else {
+				end += i + 4
+			}
+			for ; i < end; i++ {
+				if result[i] != '\n' {
+					result[i] = ' '
+				}
+			}
+			i--
+		case c == ',':
+			comma = i
+		case c == ']' || c == '}':
+			if comma >= 0 {
+				result[comma] = ' '
+			}
+			comma = -1
+		case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			comma = -1
+		}
+	}
+	return result
+}
+
+// normalizeDocument converts a parsed YAML or TOML document to a JSON document,
+// e.g. the arrays of tables of TOML to arrays of objects.
+func normalizeDocument(document interface{}) (map[string]interface{}, error) {
+	content, err := json.Marshal(document)
+	if err != nil {
+		return nil, ValidationErrors{{Message: fmt.Sprintf("unsupported value: %v", err)}}
+	}
+	return parseJSON(content)
+}
@@ -0,0 +1,278 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const overridePrefix = "PGREST_" // Prefix of the environment variables overriding settings
+
+var commandLineOverrides []string // Overrides set by command-line flags, applied after the environment variables
+
+var (
+	referencePattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`) // ${NAME}, ${NAME:-default} and ${file:path}, $${ escapes ${
+	nonAlphanumeric  = regexp.MustCompile(`[^A-Z0-9]`)
+)
+
+// interpolateDocument replaces the ${NAME}, ${NAME:-default} and ${file:path} references in the string values
+// of the parsed configuration document of type t. String values of numeric, boolean and list settings are
+// converted after interpolation, e.g. "port": "${PORT}".
+// Relative secret files are resolved against the directory of the given config file.
+func interpolateDocument(file string, document interface{}, t reflect.Type, path string, problems *ValidationErrors) interface{} {
+	switch value := document.(type) {
+	case string:
+		interpolated, err := interpolate(file, value)
+		if err != nil {
+			problems.add(path, "%v", err)
+			return value
+		}
+		if interpolated == value {
+			return value
+		}
+
+		converted, err := convertValue(interpolated, t)
+		if err != nil {
+			problems.add(path, "%v", err)
+			return interpolated
+		}
+		return converted
+	case []interface{}:
+		for i, item := range value {
+			value[i] = interpolateDocument(file, item, childType(t, ""), fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			value[key] = interpolateDocument(file, value[key], childType(t, key), joinPath(path, key), problems)
+		}
+	}
+	return document
+}
+
+// interpolate replaces the references in the string value.
+func interpolate(file string, value string) (string, error) {
+	var err error
+	interpolated := referencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		resolved, resolveErr := resolveReference(file, match[2:len(match)-1])
+		if resolveErr != nil && err == nil {
+			err = resolveErr
+		}
+		return resolved
+	})
+	return interpolated, err
+}
+
+// resolveReference returns the value of an environment variable reference or the content of a secret file reference.
+// The default of ${NAME:-default} is used when the variable is not set or empty.
+func resolveReference(file string, reference string) (string, error) {
+	if path, ok := strings.CutPrefix(reference, "file:"); ok {
+		content, err := os.ReadFile(resolvePath(file, path))
+		if err != nil {
+			return "", fmt.Errorf("error reading secret file: %v", err)
+		}
+		// Secret files usually end with a newline which is not part of the secret
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	name, fallback, hasDefault := strings.Cut(reference, ":-")
+	if name == "" {
+		return "", fmt.Errorf("invalid reference '${%s}'", reference)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if hasDefault && value == "" {
+		return fallback, nil
+	}
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// applyOverrides sets the settings of the PGREST_* environment variables in the parsed configuration document.
+// The name of a variable is the path of the setting in upper case, e.g. PGREST_PORT for pgrest.port and
+// PGREST_CONNECTIONS_DEFAULT_CONNECTIONSTRING for the connectionString of the connection named default.
+// Connections, named queries and layers are referenced by name and users by clientId, non-alphanumeric
+// characters of a name are replaced by underscores.
+func applyOverrides(document map[string]interface{}, problems *ValidationErrors) {
+	environment := os.Environ()
+	sort.Strings(environment)
+
+	for _, variable := range append(environment, commandLineOverrides...) {
+		name, value, _ := strings.Cut(variable, "=")
+		key, ok := strings.CutPrefix(name, overridePrefix)
+		if !ok || name == "PGREST_CONFIG_PATH" {
+			continue
+		}
+
+		// The settings of the pgrest section don't repeat its name, e.g. PGREST_PORT
+		path, err := setOverride(document, reflect.TypeOf(Config{}), key, value, "")
+		if path == "" {
+			path, err = setOverride(document, reflect.TypeOf(Config{}), "PGREST_"+key, value, "")
+		}
+
+		if path == "" {
+			log.Warnf("Environment variable %s does not match a setting.", name)
+		} else if err != nil {
+			problems.add(name, "invalid value for %s: %v", path, err)
+		} else {
+			log.Debugf("Setting %s overridden by environment variable %s.", path, name)
+		}
+	}
+}
+
+// SetOverride overrides the setting like the environment variable with the given name, e.g. PGREST_PORT,
+// used for command-line flags. It takes precedence over the environment variables and is kept when
+// the configuration is reloaded. It must be called before the configuration is initialized.
+func SetOverride(name string, value string) {
+	commandLineOverrides = append(commandLineOverrides, name+"="+value)
+}
+
+// setOverride sets the setting of the object of type t matching the key to the value.
+// It returns the path of the setting, or an empty path when no setting matches the key.
+func setOverride(object map[string]interface{}, t reflect.Type, key string, value string, path string) (string, error) {
+	for name, fieldType := range jsonFields(t) {
+		fieldKey := strings.ToUpper(name)
+		fieldPath := joinPath(path, name)
+		if key == fieldKey {
+			converted, err := convertValue(value, fieldType)
+			if err != nil {
+				return fieldPath, err
+			}
+			object[name] = converted
+			return fieldPath, nil
+		}
+
+		rest, ok := strings.CutPrefix(key, fieldKey+"_")
+		if !ok {
+			continue
+		}
+
+		fieldType = indirect(fieldType)
+		switch fieldType.Kind() {
+		case reflect.Struct:
+			child, ok := object[name].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+			}
+			if settingPath, err := setOverride(child, fieldType, rest, value, fieldPath); settingPath != "" {
+				object[name] = child
+				return settingPath, err
+			}
+		case reflect.Slice:
+			items, _ := object[name].([]interface{})
+			for i, item := range items {
+				element, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				elementName := elementName(element)
+				elementRest, ok := strings.CutPrefix(rest, overrideKey(elementName)+"_")
+				if !ok || elementName == "" {
+					continue
+				}
+
+				elementPath := fmt.Sprintf("%s[%d]", fieldPath, i)
+				if settingPath, err := setOverride(element, indirect(fieldType.Elem()), elementRest, value, elementPath); settingPath != "" {
+					return settingPath, err
+				}
+			}
+		case reflect.Map:
+			entries, _ := object[name].(map[string]interface{})
+			for entry := range entries {
+				if rest == overrideKey(entry) {
+					converted, err := convertValue(value, fieldType.Elem())
+					if err != nil {
+						return joinPath(fieldPath, entry), err
+					}
+					entries[entry] = converted
+					return joinPath(fieldPath, entry), nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// convertValue converts the string value to the JSON value of a setting of type t.
+// Lists are comma separated, e.g. "POST, OPTIONS".
+func convertValue(value string, t reflect.Type) (interface{}, error) {
+	if t == nil {
+		return value, nil
+	}
+
+	t = indirect(t)
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		number, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got '%s'", value)
+		}
+		return number, nil
+	case reflect.Bool:
+		boolean, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got '%s'", value)
+		}
+		return boolean, nil
+	case reflect.Slice:
+		items := []interface{}{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	return value, nil
+}
+
+// childType returns the type of the field with the given key of a struct type, or the element type
+// of a slice or map type. It returns nil when the type is unknown.
+func childType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+
+	t = indirect(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		return jsonFields(t)[key]
+	case reflect.Slice, reflect.Map:
+		return t.Elem()
+	}
+	return nil
+}
+
+// indirect returns the element type of a pointer type.
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// elementName returns the name of a connection, named query or layer, or the clientId of a user.
+func elementName(element map[string]interface{}) string {
+	if name, ok := element["name"].(string); ok {
+		return name
+	}
+	clientID, _ := element["clientId"].(string)
+	return clientID
+}
+
+// overrideKey returns the name in upper case with non-alphanumeric characters replaced by underscores.
+func overrideKey(name string) string {
+	return nonAlphanumeric.ReplaceAllString(strings.ToUpper(name), "_")
+}
@@ -3,22 +3,29 @@ package settings
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
-	"regexp"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-var config Config
+var config atomic.Pointer[Config] // The current configuration, replaced when the configuration is reloaded
 var configFile = getConfigLocation()
 
+//go:generate go run ../cmd/schema -src . -out ../../config/pgrest.schema.json
+
+// Config is the PGRest configuration loaded from the configuration file.
 type Config struct {
-	PGRest      PGRestConfig       `json:"pgrest"`
-	Connections []ConnectionConfig `json:"connections"`
-	Users       []UserConfig       `json:"users"`
-	UsersLookup map[string]UserConfig
+	PGRest      PGRestConfig          `json:"pgrest"`      // The server settings
+	Connections []ConnectionConfig    `json:"connections"` // The PostgreSQL databases which can be queried
+	Users       []UserConfig          `json:"users"`       // The users of the private connections
+	UsersLookup map[string]UserConfig `json:"-"`
 }
 
 // getConnectionConfig retrieves the connection configuration for the given name.
@@ -34,30 +41,225 @@ func (c Config) GetConnectionConfig(name string) (*ConnectionConfig, error) {
 	return nil, fmt.Errorf("connection %s not found", name)
 }
 
+// PGRestConfig contains the server settings.
 type PGRestConfig struct {
-	Port                  int        `json:"port"`
-	Debug                 bool       `json:"debug"`
-	CORS                  CorsConfig `json:"cors"`
-	MaxConcurrentRequests int        `json:"maxConcurrentRequests"`
-	Timeout               int        `json:"timeout"`
+	Port                  int                `json:"port"`                  // The HTTP port, default 8080
+	Debug                 bool               `json:"debug"`                 // Log at debug level instead of info level
+	LogLevel              string             `json:"logLevel"`              // The log level (trace, debug, info, warn or error), overrides debug
+	CORS                  CorsConfig         `json:"cors"`                  // Cross-Origin Resource Sharing settings
+	MaxConcurrentRequests int                `json:"maxConcurrentRequests"` // Maximum number of requests processed at a time, default 15
+	Timeout               int                `json:"timeoutSeconds"`        // Seconds before a request times out, default 30
+	FlightSQL             FlightSQLConfig    `json:"flightSql"`             // Arrow Flight SQL endpoint settings
+	Jobs                  JobsConfig         `json:"jobs"`                  // Asynchronous query job settings
+	Cache                 ResultCacheConfig  `json:"cache"`                 // Result cache settings
+	Cursors               CursorsConfig      `json:"cursors"`               // Pagination cursor settings
+	Transactions          TransactionsConfig `json:"transactions"`          // Interactive transaction settings
+	WatchConfig           bool               `json:"watchConfig"`           // Reload the configuration when the file or named queries change
+}
+
+// LogLevels are the supported values of the log level setting.
+var LogLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+// GetLogLevel returns the configured log level, debug when debug is set and info otherwise.
+func (p PGRestConfig) GetLogLevel() log.Level {
+	if slices.Contains(LogLevels, p.LogLevel) {
+		if level, err := log.ParseLevel(p.LogLevel); err == nil {
+			return level
+		}
+	}
+	if p.Debug {
+		return log.DebugLevel
+	}
+	return log.InfoLevel
+}
+
+// TransactionsConfig contains the settings of the interactive transactions spanning multiple requests.
+// A transaction holds a pooled database connection open until it is committed or rolled back.
+type TransactionsConfig struct {
+	IdleTimeoutSeconds int `json:"idleTimeoutSeconds"` // Seconds after which an unused transaction is rolled back, default 30
+	MaxPerUser         int `json:"maxPerUser"`         // Maximum number of open transactions per user, default 2
+}
+
+// CursorsConfig contains the settings of the cursors used to page through large query results.
+// A cursor holds a transaction and a pooled database connection open until its last page is fetched.
+type CursorsConfig struct {
+	IdleTimeoutSeconds int `json:"idleTimeoutSeconds"` // Seconds after which an unused cursor is closed, default 60
+	MaxPerUser         int `json:"maxPerUser"`         // Maximum number of open cursors per user, default 5
+	MaxPageSize        int `json:"maxPageSize"`        // Maximum number of rows per page, default 10000
+}
+
+// ResultCacheConfig contains the settings of the result cache shared by all connections.
+// Results are only cached for connections and named queries with a cache TTL.
+type ResultCacheConfig struct {
+	MaxBytes      int64  `json:"maxBytes"`      // The byte budget of the cache, default 64 MB
+	MaxEntryBytes int64  `json:"maxEntryBytes"` // The maximum size of a cached result, default 8 MB
+	Dir           string `json:"dir"`           // Store the cached results on disk in this directory instead of in memory
+}
+
+// CacheConfig contains the result cache settings of a connection or named query.
+type CacheConfig struct {
+	TTLSeconds int `json:"ttlSeconds"` // Seconds a result is cached, 0 disables caching
+}
+
+// JobsConfig contains the settings of the asynchronous query jobs.
+type JobsConfig struct {
+	Dir               string `json:"dir"`               // The directory the job results are spooled to, default "pgrest-jobs" in the temp directory
+	TTLSeconds        int    `json:"ttlSeconds"`        // Seconds a finished job and its result are kept, default 3600
+	TimeoutSeconds    int    `json:"timeoutSeconds"`    // Maximum run time of a job, default 3600
+	MaxConcurrentJobs int    `json:"maxConcurrentJobs"` // Maximum number of jobs running at a time, default 4
+}
+
+// FlightSQLConfig contains the settings of the optional Arrow Flight SQL endpoint.
+type FlightSQLConfig struct {
+	Enabled     bool   `json:"enabled"`     // Serve the Flight SQL endpoint, default false
+	Port        int    `json:"port"`        // The gRPC port, default 8815
+	TLSCertFile string `json:"tlsCertFile"` // The TLS certificate file, required as clients send their clientSecret
+	TLSKeyFile  string `json:"tlsKeyFile"`  // The TLS private key file, required
 }
 
+// ConnectionConfig is a PostgreSQL database which can be queried using /api/{name}.
 type ConnectionConfig struct {
-	Name             string `json:"name"`
-	Auth             string `json:"auth"`
-	ConnectionString string `json:"connectionString"`
+	Name             string             `json:"name"`             // The name of the connection used in the URL
+	Auth             string             `json:"auth"`             // Require authentication ("private") or not ("public"), default "private"
+	ConnectionString string             `json:"connectionString"` // The PostgreSQL connection string
+	Queries          []NamedQueryConfig `json:"queries"`          // The named queries of the connection
+	QueriesDir       string             `json:"queriesDir"`       // A directory with named queries stored as .sql files
+	ReadOnly         bool               `json:"readOnly"`         // Execute the queries in read-only transactions
+	Layers           []TileLayerConfig  `json:"layers"`           // The vector tile layers of the connection
+	Cache            CacheConfig        `json:"cache"`            // The result cache settings of the connection
+	RowLevelSecurity bool               `json:"rowLevelSecurity"` // Set the role and claims of the user local to the transaction of each query
+	LimitsConfig
 }
 
+// GetLayer retrieves the vector tile layer with the given name of the connection.
+// It returns an error if the connection has no layer with the given name.
+func (c ConnectionConfig) GetLayer(name string) (*TileLayerConfig, error) {
+	for _, layer := range c.Layers {
+		if layer.Name == name {
+			return &layer, nil
+		}
+	}
+
+	return nil, fmt.Errorf("layer %s not found for connection %s", name, c.Name)
+}
+
+// GetNamedQuery retrieves the named query with the given name from the query catalog of the connection.
+// It returns an error if the connection has no query with the given name.
+func (c ConnectionConfig) GetNamedQuery(name string) (*NamedQueryConfig, error) {
+	for _, query := range c.Queries {
+		if query.Name == name {
+			return &query, nil
+		}
+	}
+
+	return nil, fmt.Errorf("query %s not found for connection %s", name, c.Name)
+}
+
+// NamedQueryConfig is a vetted, parameterized query stored on the server
+// which clients can execute by name providing only the parameters.
+type NamedQueryConfig struct {
+	Name       string       `json:"name"`       // The name used to execute the query
+	Query      string       `json:"query"`      // The query with placeholders $1..$n for the parameters
+	ParamTypes []string     `json:"paramTypes"` // The PostgreSQL types of the parameters, e.g. "int4"
+	Cache      *CacheConfig `json:"cache"`      // Overrides the cache settings of the connection
+	file       string       // The .sql file of a query loaded from the queries directory
+}
+
+// GetCacheTTLSeconds returns the seconds the result of the named query is cached,
+// the cache settings of the named query override the settings of the connection.
+func (q NamedQueryConfig) GetCacheTTLSeconds(connection *ConnectionConfig) int {
+	if q.Cache != nil {
+		return q.Cache.TTLSeconds
+	}
+	return connection.Cache.TTLSeconds
+}
+
+// TileLayerConfig is a Mapbox Vector Tile layer served from a table or query with a geometry column.
+type TileLayerConfig struct {
+	Name           string   `json:"name"`           // The name of the layer used in the URL
+	Table          string   `json:"table"`          // The table or view, e.g. "public.buildings"
+	SQL            string   `json:"sql"`            // A query used instead of a table, can use $1, $2, $3 for z, x, y
+	GeometryColumn string   `json:"geometryColumn"` // The geometry column, default "geom"
+	SRID           int      `json:"srid"`           // The SRID of the geometry column, default 4326
+	IDColumn       string   `json:"idColumn"`       // The column used as feature id (optional)
+	Columns        []string `json:"columns"`        // The columns added as feature properties
+	Extent         int      `json:"extent"`         // The tile extent in tile coordinate space, default 4096
+	Buffer         int      `json:"buffer"`         // The buffer in tile coordinate space, default 64
+	MinZoom        int      `json:"minZoom"`        // The minimum zoom level with data, default 0
+	MaxZoom        int      `json:"maxZoom"`        // The maximum zoom level with data, default 22
+}
+
+// UserConfig is a client which can access private connections.
 type UserConfig struct {
-	ClientID     string   `json:"clientId"`
-	ClientSecret string   `json:"clientSecret"`
-	Connections  []string `json:"connections"`
+	ClientID     string              `json:"clientId"`     // The identifier of the client
+	ClientSecret string              `json:"clientSecret"` // The secret used to sign requests, only sent by Flight SQL clients using TLS
+	Connections  []string            `json:"connections"`  // The connections the user has access to
+	Queries      map[string][]string `json:"queries"`      // The named queries the user has access to per connection
+	Role         string              `json:"role"`         // The database role of the user on connections with row-level security
+	Claims       map[string]string   `json:"claims"`       // Claims set as pgrest.claims.<name> on connections with row-level security
+	LimitsConfig
 }
 
+// HasConnectionAccess checks if the user has access to the whole connection with the given name.
+func (u UserConfig) HasConnectionAccess(connection string) bool {
+	for _, c := range u.Connections {
+		if c == connection {
+			return true
+		}
+	}
+	return false
+}
+
+// HasQueryAccess checks if the user is allowed to execute the named query of the given connection.
+// Access to the whole connection implies access to all its named queries.
+func (u UserConfig) HasQueryAccess(connection string, query string) bool {
+	if u.HasConnectionAccess(connection) {
+		return true
+	}
+
+	for _, q := range u.Queries[connection] {
+		if q == query {
+			return true
+		}
+	}
+	return false
+}
+
+// LimitsConfig contains the guard rails applied when executing queries,
+// a zero value means no limit.
+type LimitsConfig struct {
+	StatementTimeoutMs int   `json:"statementTimeoutMs"` // PostgreSQL statement_timeout in milliseconds
+	MaxRows            int   `json:"maxRows"`            // Maximum number of returned rows
+	MaxResponseBytes   int64 `json:"maxResponseBytes"`   // Maximum (uncompressed) response size in bytes
+}
+
+// GetEffectiveLimits returns the limits to apply for the connection and the optional user,
+// for each limit the strictest of the connection and user limit is used.
+func GetEffectiveLimits(connection *ConnectionConfig, user *UserConfig) LimitsConfig {
+	limits := connection.LimitsConfig
+	if user == nil {
+		return limits
+	}
+
+	limits.StatementTimeoutMs = strictest(limits.StatementTimeoutMs, user.StatementTimeoutMs)
+	limits.MaxRows = strictest(limits.MaxRows, user.MaxRows)
+	limits.MaxResponseBytes = strictest(limits.MaxResponseBytes, user.MaxResponseBytes)
+	return limits
+}
+
+// strictest returns the lowest of the two limits, ignoring zero values which mean no limit.
+func strictest[T int | int64](a T, b T) T {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// CorsConfig contains the Cross-Origin Resource Sharing settings.
 type CorsConfig struct {
-	AllowOrigins []string `json:"allowOrigins"`
-	AllowHeaders []string `json:"allowHeaders"`
-	AllowMethods []string `json:"allowMethods"`
+	AllowOrigins []string `json:"allowOrigins"` // The origins allowed to access PGRest, default ["*"]
+	AllowHeaders []string `json:"allowHeaders"` // The allowed headers, default ["*"]
+	AllowMethods []string `json:"allowMethods"` // The allowed methods, default ["POST", "OPTIONS"]
 }
 
 // isOriginAllowed checks if the provided origin is allowed based on the CorsConfig settings.
@@ -98,39 +300,132 @@ func getConfigLocation() string {
 	return location
 }
 
-// InitializeConfig loads the configuration and starts watching for changes.
+// SetConfigFile sets the location of the configuration file, overriding PGREST_CONFIG_PATH.
+// It must be called before the configuration is initialized.
+func SetConfigFile(path string) {
+	configFile = path
+}
+
+// GetConfigFile returns the location of the configuration file.
+func GetConfigFile() string {
+	return configFile
+}
+
+// InitializeConfig loads the configuration.
 // It returns an error if there was a problem loading the configuration.
 func InitializeConfig() error {
-	err := loadConfig()
+	loaded, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
+	config.Store(loaded)
 	return nil
 }
 
-// loadConfig loads the configuration from a JSON file.
-// It reads the JSON file, unmarshals it into the 'config' variable,
-// and sets default values if necessary.
-// Returns an error if there was a problem reading or unmarshaling the JSON file.
-func loadConfig() error {
-	jsonFile, err := os.Open(configFile)
+// ReloadConfig loads the configuration again and atomically replaces the current configuration,
+// requests started after the reload use the new configuration. When the new configuration is invalid
+// the current configuration is kept and the error is returned.
+// It returns the previous and the new configuration.
+func ReloadConfig() (Config, Config, error) {
+	previous := GetConfig()
+
+	loaded, err := loadConfig()
 	if err != nil {
-		return err
+		return previous, previous, err
+	}
+
+	config.Store(loaded)
+	return previous, *loaded, nil
+}
+
+// WatchConfig polls the modification time of the configuration file and the named query directories,
+// calling reload when one of them changed.
+func WatchConfig(interval time.Duration, reload func()) {
+	lastModified := configModTime(GetConfig())
+	for {
+		time.Sleep(interval)
+
+		modified := configModTime(GetConfig())
+		if !modified.Equal(lastModified) {
+			lastModified = modified
+			reload()
+		}
+	}
+}
+
+// configModTime returns the latest modification time of the configuration file and the .sql files
+// in the named query directories of the configuration.
+func configModTime(current Config) time.Time {
+	var latest time.Time
+	paths := []string{configFile}
+	for _, conn := range current.Connections {
+		if conn.QueriesDir != "" {
+			dir := resolvePath(configFile, conn.QueriesDir)
+			files, _ := filepath.Glob(filepath.Join(dir, "*.sql"))
+			paths = append(paths, dir)
+			paths = append(paths, files...)
+		}
+	}
+
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
 	}
-	defer jsonFile.Close()
+	return latest
+}
 
-	byteValue, err := io.ReadAll(jsonFile)
+// LoadConfig loads and validates the configuration file at the given path without using it.
+// It returns ValidationErrors with all problems found in the configuration.
+func LoadConfig(path string) (Config, error) {
+	loaded, err := loadConfigFile(path)
 	if err != nil {
-		return err
+		return Config{}, err
 	}
+	return *loaded, nil
+}
+
+// loadConfig loads the configuration from the configuration file.
+func loadConfig() (*Config, error) {
+	return loadConfigFile(configFile)
+}
 
-	// Preprocess the JSON to remove excessive commas
-	cleanedJSON := cleanJSON(string(byteValue))
+// loadConfigFile loads the configuration from a JSON, YAML or TOML file.
+// It reads the file, unmarshals it into a new configuration,
+// sets default values if necessary and validates the configuration.
+// Returns an error if there was a problem reading or parsing the file,
+// or ValidationErrors with all problems found in the configuration.
+func loadConfigFile(path string) (*Config, error) {
+	config := &Config{}
 
-	err = json.Unmarshal([]byte(cleanedJSON), &config)
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	document, err := parseDocument(path, content)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems ValidationErrors
+	interpolateDocument(path, document, reflect.TypeOf(Config{}), "", &problems)
+	applyOverrides(document, &problems)
+	validateFields(document, reflect.TypeOf(Config{}), "", &problems)
+
+	resolved, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(resolved, config)
+	if err != nil {
+		if len(problems) > 0 {
+			// A value which could not be converted causes the type error, report the problems instead
+			return nil, problems
+		}
+		return nil, parseError(err, resolved)
 	}
 
 	if config.PGRest.Port == 0 {
@@ -145,6 +440,54 @@ func loadConfig() error {
 		config.PGRest.Timeout = 30
 	}
 
+	if config.PGRest.FlightSQL.Port == 0 {
+		config.PGRest.FlightSQL.Port = 8815
+	}
+
+	if config.PGRest.Jobs.Dir == "" {
+		config.PGRest.Jobs.Dir = filepath.Join(os.TempDir(), "pgrest-jobs")
+	}
+
+	if config.PGRest.Jobs.TTLSeconds == 0 {
+		config.PGRest.Jobs.TTLSeconds = 3600
+	}
+
+	if config.PGRest.Jobs.TimeoutSeconds == 0 {
+		config.PGRest.Jobs.TimeoutSeconds = 3600
+	}
+
+	if config.PGRest.Jobs.MaxConcurrentJobs == 0 {
+		config.PGRest.Jobs.MaxConcurrentJobs = 4
+	}
+
+	if config.PGRest.Cache.MaxBytes == 0 {
+		config.PGRest.Cache.MaxBytes = 64 * 1024 * 1024
+	}
+
+	if config.PGRest.Cache.MaxEntryBytes == 0 {
+		config.PGRest.Cache.MaxEntryBytes = 8 * 1024 * 1024
+	}
+
+	if config.PGRest.Cursors.IdleTimeoutSeconds == 0 {
+		config.PGRest.Cursors.IdleTimeoutSeconds = 60
+	}
+
+	if config.PGRest.Cursors.MaxPerUser == 0 {
+		config.PGRest.Cursors.MaxPerUser = 5
+	}
+
+	if config.PGRest.Cursors.MaxPageSize == 0 {
+		config.PGRest.Cursors.MaxPageSize = 10000
+	}
+
+	if config.PGRest.Transactions.IdleTimeoutSeconds == 0 {
+		config.PGRest.Transactions.IdleTimeoutSeconds = 30
+	}
+
+	if config.PGRest.Transactions.MaxPerUser == 0 {
+		config.PGRest.Transactions.MaxPerUser = 2
+	}
+
 	// if debug is not set, default to false
 	if !config.PGRest.Debug {
 		config.PGRest.Debug = false
@@ -163,32 +506,130 @@ func loadConfig() error {
 	}
 
 	// iterate over connections and set default values
-	for _, conn := range config.Connections {
+	for i := range config.Connections {
+		conn := &config.Connections[i]
 		if conn.Auth == "" {
 			conn.Auth = "private"
-		} else if conn.Auth != "public" {
+		} else if conn.Auth == "public" {
 			log.Warnf("Auth for connection '%s' set to public.", conn.Name)
 		}
 	}
 
+	// set the default values of the vector tile layers
+	for i := range config.Connections {
+		for j := range config.Connections[i].Layers {
+			layer := &config.Connections[i].Layers[j]
+			if layer.GeometryColumn == "" {
+				layer.GeometryColumn = "geom"
+			}
+			if layer.SRID == 0 {
+				layer.SRID = 4326
+			}
+			if layer.Extent == 0 {
+				layer.Extent = 4096
+			}
+			if layer.Buffer == 0 {
+				layer.Buffer = 64
+			}
+			if layer.MaxZoom == 0 {
+				layer.MaxZoom = 22
+			}
+		}
+	}
+
+	// load the named queries stored as .sql files
+	for i := range config.Connections {
+		conn := &config.Connections[i]
+		if conn.QueriesDir == "" {
+			continue
+		}
+
+		queries, err := loadQueriesDir(path, conn.QueriesDir)
+		if err != nil {
+			problems.add(fmt.Sprintf("connections[%d].queriesDir", i), "error loading queries: %v", err)
+			continue
+		}
+		conn.Queries = append(conn.Queries, queries...)
+	}
+
+	problems = append(problems, config.validate()...)
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
 	config.UsersLookup = make(map[string]UserConfig)
 	for _, user := range config.Users {
 		config.UsersLookup[user.ClientID] = user
 	}
 
-	return nil
+	return config, nil
+}
+
+// loadQueriesDir loads the named queries from the .sql files in the given directory.
+// The name of a query is the file name without extension. The param types and cache TTL can be set
+// using comment lines in the file, e.g. "-- paramTypes: int4, timestamptz" and "-- cacheTtlSeconds: 60".
+// A relative directory is resolved against the directory of the given config file.
+func loadQueriesDir(file string, dir string) ([]NamedQueryConfig, error) {
+	dir = resolvePath(file, dir)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]NamedQueryConfig, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		query := NamedQueryConfig{
+			Name:  strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)),
+			Query: strings.TrimSpace(string(content)),
+			file:  file,
+		}
+
+		for _, line := range strings.Split(query.Query, "\n") {
+			comment, ok := strings.CutPrefix(strings.TrimSpace(line), "--")
+			if !ok {
+				continue
+			}
+
+			if types, ok := strings.CutPrefix(strings.TrimSpace(comment), "paramTypes:"); ok {
+				for _, t := range strings.Split(types, ",") {
+					query.ParamTypes = append(query.ParamTypes, strings.TrimSpace(t))
+				}
+			}
+
+			if ttl, ok := strings.CutPrefix(strings.TrimSpace(comment), "cacheTtlSeconds:"); ok {
+				seconds, err := strconv.Atoi(strings.TrimSpace(ttl))
+				if err != nil {
+					return nil, fmt.Errorf("invalid cacheTtlSeconds in %s: %v", file, err)
+				}
+				query.Cache = &CacheConfig{TTLSeconds: seconds}
+			}
+		}
+
+		queries = append(queries, query)
+	}
+
+	return queries, nil
 }
 
-func cleanJSON(input string) string {
-	// Remove trailing commas before closing braces and brackets
-	re := regexp.MustCompile(`,\s*([\]}])`)
-	cleaned := re.ReplaceAllString(input, "$1")
-	// Ensure that there are no consecutive commas
-	cleaned = strings.ReplaceAll(cleaned, ",,", ",")
-	return cleaned
+// resolvePath resolves a relative path against the directory of the given config file.
+func resolvePath(file string, path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.Join(filepath.Dir(file), path)
+	}
+	return path
 }
 
 // GetConfig returns the current configuration.
 func GetConfig() Config {
-	return config
+	current := config.Load()
+	if current == nil {
+		return Config{}
+	}
+	return *current
 }
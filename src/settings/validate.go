@@ -0,0 +1,392 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	namePattern  = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)        // Names used in URL paths
+	claimPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`) // Claims are set as pgrest.claims.<name>
+)
+
+// ValidationError is a problem in the configuration at the JSON path of the setting, e.g. "connections[0].name".
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors contains all problems found in the configuration.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	problems := make([]string, len(e))
+	for i, problem := range e {
+		problems[i] = "  - " + problem.Error()
+	}
+	return fmt.Sprintf("invalid configuration, %d problem(s):\n%s", len(e), strings.Join(problems, "\n"))
+}
+
+// add adds a problem at the given path.
+func (e *ValidationErrors) add(path string, format string, args ...interface{}) {
+	*e = append(*e, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the configuration after the default values are set.
+// It returns ValidationErrors with all problems found, or nil when the configuration is valid.
+func (c Config) Validate() error {
+	if problems := c.validate(); len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func (c Config) validate() ValidationErrors {
+	var problems ValidationErrors
+	c.PGRest.validate(&problems)
+
+	if len(c.Connections) == 0 {
+		problems.add("connections", "at least one connection is required")
+	}
+
+	connections := make(map[string]*ConnectionConfig)
+	for i := range c.Connections {
+		conn := &c.Connections[i]
+		path := fmt.Sprintf("connections[%d]", i)
+		conn.validate(path, &problems)
+
+		if _, ok := connections[conn.Name]; ok && conn.Name != "" {
+			problems.add(path+".name", "duplicate connection name '%s'", conn.Name)
+		}
+		connections[conn.Name] = conn
+	}
+
+	clientIDs := make(map[string]bool)
+	for i, user := range c.Users {
+		path := fmt.Sprintf("users[%d]", i)
+		user.validate(path, connections, &problems)
+
+		if clientIDs[user.ClientID] && user.ClientID != "" {
+			problems.add(path+".clientId", "duplicate clientId '%s'", user.ClientID)
+		}
+		clientIDs[user.ClientID] = true
+	}
+
+	return problems
+}
+
+func (p PGRestConfig) validate(problems *ValidationErrors) {
+	validatePort("pgrest.port", p.Port, problems)
+	if p.LogLevel != "" && !slices.Contains(LogLevels, p.LogLevel) {
+		problems.add("pgrest.logLevel", "must be one of %s, got '%s'", strings.Join(LogLevels, ", "), p.LogLevel)
+	}
+	validatePositive("pgrest.maxConcurrentRequests", p.MaxConcurrentRequests, problems)
+	validatePositive("pgrest.timeoutSeconds", p.Timeout, problems)
+
+	for i, origin := range p.CORS.AllowOrigins {
+		if origin == "*" && len(p.CORS.AllowOrigins) > 1 {
+			problems.add(fmt.Sprintf("pgrest.cors.allowOrigins[%d]", i), "'*' can not be combined with other origins")
+		}
+	}
+
+	if p.FlightSQL.Enabled {
+		validatePort("pgrest.flightSql.port", p.FlightSQL.Port, problems)
+		if p.FlightSQL.Port == p.Port {
+			problems.add("pgrest.flightSql.port", "port %d is already used by pgrest.port", p.Port)
+		}
+	}
+	if (p.FlightSQL.TLSCertFile == "") != (p.FlightSQL.TLSKeyFile == "") {
+		problems.add("pgrest.flightSql", "tlsCertFile and tlsKeyFile must be set together")
+	} else if p.FlightSQL.Enabled && p.FlightSQL.TLSCertFile == "" {
+		// Clients send the clientSecret as basic auth password
+		problems.add("pgrest.flightSql", "tlsCertFile and tlsKeyFile are required when enabled")
+	}
+
+	validatePositive("pgrest.jobs.ttlSeconds", p.Jobs.TTLSeconds, problems)
+	validatePositive("pgrest.jobs.timeoutSeconds", p.Jobs.TimeoutSeconds, problems)
+	validatePositive("pgrest.jobs.maxConcurrentJobs", p.Jobs.MaxConcurrentJobs, problems)
+
+	validatePositive("pgrest.cache.maxBytes", p.Cache.MaxBytes, problems)
+	validatePositive("pgrest.cache.maxEntryBytes", p.Cache.MaxEntryBytes, problems)
+	if p.Cache.MaxEntryBytes > p.Cache.MaxBytes {
+		problems.add("pgrest.cache.maxEntryBytes", "must not be larger than maxBytes")
+	}
+
+	validatePositive("pgrest.cursors.idleTimeoutSeconds", p.Cursors.IdleTimeoutSeconds, problems)
+	validatePositive("pgrest.cursors.maxPerUser", p.Cursors.MaxPerUser, problems)
+	validatePositive("pgrest.cursors.maxPageSize", p.Cursors.MaxPageSize, problems)
+
+	validatePositive("pgrest.transactions.idleTimeoutSeconds", p.Transactions.IdleTimeoutSeconds, problems)
+	validatePositive("pgrest.transactions.maxPerUser", p.Transactions.MaxPerUser, problems)
+}
+
+func (c ConnectionConfig) validate(path string, problems *ValidationErrors) {
+	validateName(path+".name", c.Name, problems)
+
+	if c.Auth != "private" && c.Auth != "public" {
+		problems.add(path+".auth", "must be 'private' or 'public', got '%s'", c.Auth)
+	}
+
+	if c.ConnectionString == "" {
+		problems.add(path+".connectionString", "is required")
+	} else if _, err := pgconn.ParseConfig(c.ConnectionString); err != nil {
+		// The error contains the connection string with the password redacted, except in the wrapped URL parse error
+		message := strings.ReplaceAll(err.Error(), c.ConnectionString, "...")
+		problems.add(path+".connectionString", "%s", message)
+	}
+
+	validateNotNegative(path+".cache.ttlSeconds", c.Cache.TTLSeconds, problems)
+	c.LimitsConfig.validate(path, problems)
+
+	queries := make(map[string]bool)
+	for i, query := range c.Queries {
+		queryPath := fmt.Sprintf("%s.queries[%d]", path, i)
+		if query.file != "" {
+			queryPath = fmt.Sprintf("%s.queriesDir[%s]", path, filepath.Base(query.file))
+		}
+		query.validate(queryPath, problems)
+
+		if queries[query.Name] && query.Name != "" {
+			problems.add(queryPath+".name", "duplicate query name '%s'", query.Name)
+		}
+		queries[query.Name] = true
+	}
+
+	layers := make(map[string]bool)
+	for i, layer := range c.Layers {
+		layerPath := fmt.Sprintf("%s.layers[%d]", path, i)
+		layer.validate(layerPath, problems)
+
+		if layers[layer.Name] && layer.Name != "" {
+			problems.add(layerPath+".name", "duplicate layer name '%s'", layer.Name)
+		}
+		layers[layer.Name] = true
+	}
+}
+
+func (q NamedQueryConfig) validate(path string, problems *ValidationErrors) {
+	validateName(path+".name", q.Name, problems)
+
+	if strings.TrimSpace(q.Query) == "" {
+		problems.add(path+".query", "is required")
+	}
+
+	for i, paramType := range q.ParamTypes {
+		if strings.TrimSpace(paramType) == "" {
+			problems.add(fmt.Sprintf("%s.paramTypes[%d]", path, i), "must not be empty")
+		}
+	}
+
+	if q.Cache != nil {
+		validateNotNegative(path+".cache.ttlSeconds", q.Cache.TTLSeconds, problems)
+	}
+}
+
+func (l TileLayerConfig) validate(path string, problems *ValidationErrors) {
+	validateName(path+".name", l.Name, problems)
+
+	if (l.Table == "") == (l.SQL == "") {
+		problems.add(path, "exactly one of table and sql must be set")
+	}
+
+	validatePositive(path+".srid", l.SRID, problems)
+	validatePositive(path+".extent", l.Extent, problems)
+	validateNotNegative(path+".buffer", l.Buffer, problems)
+
+	if l.MinZoom < 0 || l.MinZoom > 30 {
+		problems.add(path+".minZoom", "must be between 0 and 30, got %d", l.MinZoom)
+	}
+	if l.MaxZoom < 0 || l.MaxZoom > 30 {
+		problems.add(path+".maxZoom", "must be between 0 and 30, got %d", l.MaxZoom)
+	}
+	if l.MinZoom > l.MaxZoom {
+		problems.add(path+".minZoom", "must not be larger than maxZoom")
+	}
+}
+
+func (u UserConfig) validate(path string, connections map[string]*ConnectionConfig, problems *ValidationErrors) {
+	if u.ClientID == "" {
+		problems.add(path+".clientId", "is required")
+	}
+	if u.ClientSecret == "" {
+		problems.add(path+".clientSecret", "is required")
+	}
+
+	for i, name := range u.Connections {
+		if _, ok := connections[name]; !ok {
+			problems.add(fmt.Sprintf("%s.connections[%d]", path, i), "unknown connection '%s'", name)
+		}
+	}
+
+	for _, name := range sortedKeys(u.Queries) {
+		conn, ok := connections[name]
+		if !ok {
+			problems.add(fmt.Sprintf("%s.queries.%s", path, name), "unknown connection '%s'", name)
+			continue
+		}
+
+		for i, query := range u.Queries[name] {
+			if _, err := conn.GetNamedQuery(query); err != nil {
+				problems.add(fmt.Sprintf("%s.queries.%s[%d]", path, name, i), "unknown query '%s' of connection '%s'", query, name)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(u.Claims) {
+		if !claimPattern.MatchString(name) {
+			problems.add(fmt.Sprintf("%s.claims.%s", path, name), "claim names may only contain letters, digits and underscores")
+		}
+	}
+
+	u.LimitsConfig.validate(path, problems)
+}
+
+func (l LimitsConfig) validate(path string, problems *ValidationErrors) {
+	validateNotNegative(path+".statementTimeoutMs", l.StatementTimeoutMs, problems)
+	validateNotNegative(path+".maxRows", l.MaxRows, problems)
+	validateNotNegative(path+".maxResponseBytes", l.MaxResponseBytes, problems)
+}
+
+func validateName(path string, name string, problems *ValidationErrors) {
+	if name == "" {
+		problems.add(path, "is required")
+	} else if !namePattern.MatchString(name) {
+		problems.add(path, "'%s' may only contain letters, digits, '_', '-' and '.'", name)
+	}
+}
+
+func validatePort(path string, port int, problems *ValidationErrors) {
+	if port < 1 || port > 65535 {
+		problems.add(path, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+func validatePositive[T int | int64](path string, value T, problems *ValidationErrors) {
+	if value <= 0 {
+		problems.add(path, "must be larger than 0, got %d", value)
+	}
+}
+
+func validateNotNegative[T int | int64](path string, value T, problems *ValidationErrors) {
+	if value < 0 {
+		problems.add(path, "must not be negative, got %d", value)
+	}
+}
+
+// validateFields checks the parsed JSON document for fields which do not exist in the configuration,
+// which would otherwise be silently ignored, e.g. a misspelled setting.
+func validateFields(document interface{}, t reflect.Type, path string, problems *ValidationErrors) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		validateFields(document, t.Elem(), path, problems)
+	case reflect.Slice:
+		if items, ok := document.([]interface{}); ok {
+			for i, item := range items {
+				validateFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case reflect.Map:
+		if values, ok := document.(map[string]interface{}); ok {
+			for _, key := range sortedKeys(values) {
+				validateFields(values[key], t.Elem(), joinPath(path, key), problems)
+			}
+		}
+	case reflect.Struct:
+		values, ok := document.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(values) {
+			if path == "" && key == "$schema" {
+				continue // The JSON Schema of the configuration used by editors
+			}
+
+			field, ok := fields[key]
+			if ok {
+				validateFields(values[key], field, joinPath(path, key), problems)
+				continue
+			}
+
+			message := "unknown field"
+			for name := range fields {
+				if strings.EqualFold(name, key) {
+					message = fmt.Sprintf("unknown field, did you mean '%s'?", name)
+				}
+			}
+			problems.add(joinPath(path, key), "%s", message)
+		}
+	}
+}
+
+// jsonFields returns the types of the JSON fields of the struct type, including the fields of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			for embeddedName, embeddedType := range jsonFields(field.Type) {
+				fields[embeddedName] = embeddedType
+			}
+			continue
+		}
+
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// parseError converts an error of parsing the configuration file to a ValidationError with the path or line of the problem.
+func parseError(err error, content []byte) error {
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		line := 1 + strings.Count(string(content[:err.Offset]), "\n")
+		return ValidationErrors{{Message: fmt.Sprintf("line %d: %v", line, err)}}
+	case *json.UnmarshalTypeError:
+		return ValidationErrors{{Path: err.Field, Message: fmt.Sprintf("expected %s, got %s", err.Type, err.Value)}}
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ValidationErrors{{Message: "unexpected end of the configuration"}}
+	}
+	return err
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,246 @@
+package settings
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestValidateFields(t *testing.T) {
+	document := map[string]interface{}{
+		"$schema": "pgrest.schema.json",
+		"pgrest":  map[string]interface{}{"port": 8080, "logleve": "info", "LogLevel": "debug"},
+		"connections": []interface{}{
+			map[string]interface{}{"name": "default", "maxRows%d": 10},
+		},
+		"users": []interface{}{
+			map[string]interface{}{"clientId": "pgrest", "claims": map[string]interface{}{"any": "claim"}},
+		},
+	}
+
+	var problems ValidationErrors
+	validateFields(document, reflect.TypeOf(Config{}), "", &problems)
+
+	want := ValidationErrors{
+		{Path: "connections[0].maxRows%d", Message: "unknown field"},
+		{Path: "pgrest.LogLevel", Message: "unknown field, did you mean 'logLevel'?"},
+		{Path: "pgrest.logleve", Message: "unknown field"},
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("validateFields problems = %v, want %v", problems, want)
+	}
+}
+
+// loadConfigProblems loads the JSON configuration and returns the validation problems.
+func loadConfigProblems(t *testing.T, content string) (Config, ValidationErrors) {
+	t.Helper()
+
+	config, err := LoadConfig(writeConfig(t, "pgrest.json", content))
+	if err == nil {
+		return config, nil
+	}
+
+	problems, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	return config, problems
+}
+
+func TestConfigValidate(t *testing.T) {
+	const (
+		conn = `{"name": "default", "connectionString": "postgres://localhost/db", "queries": [{"name": "by_city", "query": "SELECT 1"}]}`
+		user = `{"clientId": "pgrest", "clientSecret": "secret", "connections": ["default"]}`
+	)
+
+	tests := []struct {
+		name    string
+		content string
+		want    ValidationErrors
+	}{
+		{
+			name:    "valid",
+			content: `{"connections": [` + conn + `], "users": [` + user + `]}`,
+		},
+		{
+			name:    "no connections",
+			content: `{"connections": []}`,
+			want:    ValidationErrors{{Path: "connections", Message: "at least one connection is required"}},
+		},
+		{
+			name:    "duplicate connection name",
+			content: `{"connections": [` + conn + `, ` + conn + `]}`,
+			want:    ValidationErrors{{Path: "connections[1].name", Message: "duplicate connection name 'default'"}},
+		},
+		{
+			name:    "duplicate clientId",
+			content: `{"connections": [` + conn + `], "users": [` + user + `, ` + user + `]}`,
+			want:    ValidationErrors{{Path: "users[1].clientId", Message: "duplicate clientId 'pgrest'"}},
+		},
+		{
+			name:    "user with unknown connection",
+			content: `{"connections": [` + conn + `], "users": [{"clientId": "pgrest", "clientSecret": "secret", "connections": ["default", "other"]}]}`,
+			want:    ValidationErrors{{Path: "users[0].connections[1]", Message: "unknown connection 'other'"}},
+		},
+		{
+			name:    "user with queries of unknown connection",
+			content: `{"connections": [` + conn + `], "users": [{"clientId": "pgrest", "clientSecret": "secret", "queries": {"other": ["by_city"]}}]}`,
+			want:    ValidationErrors{{Path: "users[0].queries.other", Message: "unknown connection 'other'"}},
+		},
+		{
+			name:    "user with unknown query",
+			content: `{"connections": [` + conn + `], "users": [{"clientId": "pgrest", "clientSecret": "secret", "queries": {"default": ["by_city", "by_country"]}}]}`,
+			want:    ValidationErrors{{Path: "users[0].queries.default[1]", Message: "unknown query 'by_country' of connection 'default'"}},
+		},
+		{
+			name:    "user without credentials",
+			content: `{"connections": [` + conn + `], "users": [{"connections": ["default"]}]}`,
+			want: ValidationErrors{
+				{Path: "users[0].clientId", Message: "is required"},
+				{Path: "users[0].clientSecret", Message: "is required"},
+			},
+		},
+		{
+			name:    "invalid claim name",
+			content: `{"connections": [` + conn + `], "users": [{"clientId": "pgrest", "clientSecret": "secret", "claims": {"a-b": "c"}}]}`,
+			want:    ValidationErrors{{Path: "users[0].claims.a-b", Message: "claim names may only contain letters, digits and underscores"}},
+		},
+		{
+			name:    "invalid auth",
+			content: `{"connections": [{"name": "default", "connectionString": "postgres://localhost/db", "auth": "Public"}]}`,
+			want:    ValidationErrors{{Path: "connections[0].auth", Message: "must be 'private' or 'public', got 'Public'"}},
+		},
+		{
+			name:    "connection without name and connection string",
+			content: `{"connections": [{}]}`,
+			want: ValidationErrors{
+				{Path: "connections[0].name", Message: "is required"},
+				{Path: "connections[0].connectionString", Message: "is required"},
+			},
+		},
+		{
+			name:    "invalid connection name",
+			content: `{"connections": [{"name": "my db", "connectionString": "postgres://localhost/db"}]}`,
+			want:    ValidationErrors{{Path: "connections[0].name", Message: "'my db' may only contain letters, digits, '_', '-' and '.'"}},
+		},
+		{
+			name:    "negative limits",
+			content: `{"connections": [{"name": "default", "connectionString": "postgres://localhost/db", "maxRows": -1}], "users": [{"clientId": "pgrest", "clientSecret": "secret", "maxResponseBytes": -1}]}`,
+			want: ValidationErrors{
+				{Path: "connections[0].maxRows", Message: "must not be negative, got -1"},
+				{Path: "users[0].maxResponseBytes", Message: "must not be negative, got -1"},
+			},
+		},
+		{
+			name:    "duplicate and empty named query",
+			content: `{"connections": [{"name": "default", "connectionString": "postgres://localhost/db", "queries": [{"name": "q", "query": "SELECT 1"}, {"name": "q", "query": " "}]}]}`,
+			want: ValidationErrors{
+				{Path: "connections[0].queries[1].query", Message: "is required"},
+				{Path: "connections[0].queries[1].name", Message: "duplicate query name 'q'"},
+			},
+		},
+		{
+			name:    "tile layer without table or sql",
+			content: `{"connections": [{"name": "default", "connectionString": "postgres://localhost/db", "layers": [{"name": "roads"}]}]}`,
+			want:    ValidationErrors{{Path: "connections[0].layers[0]", Message: "exactly one of table and sql must be set"}},
+		},
+		{
+			name:    "invalid log level",
+			content: `{"pgrest": {"logLevel": "verbose"}, "connections": [` + conn + `]}`,
+			want:    ValidationErrors{{Path: "pgrest.logLevel", Message: "must be one of trace, debug, info, warn, error, got 'verbose'"}},
+		},
+		{
+			name:    "wildcard combined with other origins",
+			content: `{"pgrest": {"cors": {"allowOrigins": ["https://example.com", "*"]}}, "connections": [` + conn + `]}`,
+			want:    ValidationErrors{{Path: "pgrest.cors.allowOrigins[1]", Message: "'*' can not be combined with other origins"}},
+		},
+		{
+			name:    "cache entry larger than cache",
+			content: `{"pgrest": {"cache": {"maxBytes": 1024, "maxEntryBytes": 2048}}, "connections": [` + conn + `]}`,
+			want:    ValidationErrors{{Path: "pgrest.cache.maxEntryBytes", Message: "must not be larger than maxBytes"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, problems := loadConfigProblems(t, test.content); !reflect.DeepEqual(problems, test.want) {
+				t.Errorf("LoadConfig problems = %v, want %v", problems, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigAuth(t *testing.T) {
+	tests := []struct {
+		auth     string
+		want     string
+		wantWarn bool
+	}{
+		{auth: "", want: "private"},
+		{auth: "private", want: "private"},
+		{auth: "public", want: "public", wantWarn: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want+" "+test.auth, func(t *testing.T) {
+			hook := logtest.NewGlobal()
+			defer hook.Reset()
+
+			config, problems := loadConfigProblems(t, `{"connections": [{"name": "default", "connectionString": "postgres://localhost/db", "auth": "`+test.auth+`"}]}`)
+			if problems != nil {
+				t.Fatalf("LoadConfig problems = %v", problems)
+			}
+			if got := config.Connections[0].Auth; got != test.want {
+				t.Errorf("auth = %q, want %q", got, test.want)
+			}
+
+			warned := false
+			for _, entry := range hook.AllEntries() {
+				if entry.Level == log.WarnLevel && strings.Contains(entry.Message, "public") {
+					warned = true
+				}
+			}
+			if warned != test.wantWarn {
+				t.Errorf("public warning logged = %v, want %v", warned, test.wantWarn)
+			}
+		})
+	}
+}
+
+func TestValidateFlightSQLTLS(t *testing.T) {
+	tests := []struct {
+		name      string
+		flightSQL string
+		want      ValidationErrors
+	}{
+		{name: "disabled without TLS", flightSQL: `{}`},
+		{name: "enabled with TLS", flightSQL: `{"enabled": true, "tlsCertFile": "cert.pem", "tlsKeyFile": "key.pem"}`},
+		{
+			name:      "enabled without TLS",
+			flightSQL: `{"enabled": true}`,
+			want:      ValidationErrors{{Path: "pgrest.flightSql", Message: "tlsCertFile and tlsKeyFile are required when enabled"}},
+		},
+		{
+			name:      "enabled without key",
+			flightSQL: `{"enabled": true, "tlsCertFile": "cert.pem"}`,
+			want:      ValidationErrors{{Path: "pgrest.flightSql", Message: "tlsCertFile and tlsKeyFile must be set together"}},
+		},
+		{
+			name:      "same port as HTTP",
+			flightSQL: `{"enabled": true, "port": 8080, "tlsCertFile": "cert.pem", "tlsKeyFile": "key.pem"}`,
+			want:      ValidationErrors{{Path: "pgrest.flightSql.port", Message: "port 8080 is already used by pgrest.port"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := `{"pgrest": {"flightSql": ` + test.flightSQL + `}, "connections": [{"name": "default", "connectionString": "postgres://localhost/db"}]}`
+			if _, problems := loadConfigProblems(t, content); !reflect.DeepEqual(problems, test.want) {
+				t.Errorf("LoadConfig problems = %v, want %v", problems, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package transactions
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sogelink-research/pgrest/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Kind is the purpose a transaction is held open for.
+type Kind string
+
+const (
+	KindCursor      Kind = "cursor"      // A cursor returning a query result in pages
+	KindTransaction Kind = "transaction" // An interactive transaction committed or rolled back by the client
+)
+
+var (
+	transactions      = make(map[string]*Transaction) // Map to store the open transactions by id
+	transactionsMutex sync.Mutex                      // Mutex to ensure thread safety for transactions
+	tokenSecret       = make([]byte, 32)              // Secret to sign the tokens, tokens are invalid after a restart
+	cleanupInterval   = 5 * time.Second               // Interval to check for idle transactions
+)
+
+// init is called before the main function.
+// It generates the token secret and starts a goroutine to periodically roll back idle transactions.
+func init() {
+	if _, err := rand.Read(tokenSecret); err != nil {
+		panic(err)
+	}
+
+	go periodicCleanup()
+}
+
+// Limits contains the limits of the transactions of a kind.
+type Limits struct {
+	MaxPerUser  int           // Maximum number of open transactions of the kind per user
+	IdleTimeout time.Duration // Time after which an unused transaction is rolled back
+}
+
+// Cursor is the state of a cursor declared in a transaction of kind KindCursor.
+type Cursor struct {
+	Name     string
+	PageSize int
+	Fields   []pgconn.FieldDescription
+	Pending  [][]interface{} // Rows fetched ahead to detect if there is a next page
+	Returned int             // Number of rows returned in the pages fetched so far
+}
+
+// Transaction is a transaction held open across requests on a connection acquired from the pool.
+// A transaction is used by one request at a time, requests acquire it by its token.
+type Transaction struct {
+	ID         string
+	Kind       Kind
+	Connection string // The name of the connection the transaction is on
+	ClientID   string // The user who opened the transaction, empty for public connections
+	Tx         pgx.Tx
+	Cursor     *Cursor
+
+	conn        *pgxpool.Conn
+	busy        sync.Mutex
+	idleTimeout time.Duration
+	lastUsed    time.Time
+	closed      bool
+}
+
+// periodicCleanup is a goroutine that periodically rolls back the transactions which are idle longer than their idle timeout.
+func periodicCleanup() {
+	for {
+		time.Sleep(cleanupInterval)
+
+		transactionsMutex.Lock()
+		var idle []*Transaction
+		for _, t := range transactions {
+			idle = append(idle, t)
+		}
+		transactionsMutex.Unlock()
+
+		for _, t := range idle {
+			if !t.busy.TryLock() {
+				continue
+			}
+
+			if !t.closed && time.Since(t.lastUsed) > t.idleTimeout {
+				log.Debugf("Rolling back idle %s transaction on connection '%s'", t.Kind, t.Connection)
+				t.Close(context.Background(), false)
+			}
+			t.busy.Unlock()
+		}
+	}
+}
+
+// Begin acquires a connection from the pool, begins a transaction and registers it.
+// It returns an APIError when the user has reached the maximum number of open transactions of the kind.
+// The returned transaction is acquired by the caller, which must call Release when done.
+func Begin(ctx context.Context, pool *pgxpool.Pool, txOptions pgx.TxOptions, kind Kind, connection string, clientID string, limits Limits) (*Transaction, error) {
+	if countOpen(kind, clientID) >= limits.MaxPerUser {
+		return nil, limitError(kind, limits)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.BeginTx(ctx, txOptions)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	t := &Transaction{
+		ID:          id,
+		Kind:        kind,
+		Connection:  connection,
+		ClientID:    clientID,
+		Tx:          tx,
+		conn:        conn,
+		idleTimeout: limits.IdleTimeout,
+		lastUsed:    time.Now(),
+	}
+	t.busy.Lock()
+
+	transactionsMutex.Lock()
+	defer transactionsMutex.Unlock()
+
+	// Check again, another request of the user may have opened a transaction in the meantime
+	if countOpenLocked(kind, clientID) >= limits.MaxPerUser {
+		tx.Rollback(ctx)
+		conn.Release()
+		return nil, limitError(kind, limits)
+	}
+
+	transactions[id] = t
+	return t, nil
+}
+
+// Acquire returns the open transaction of the kind for the token, the token must be issued for the
+// same user and connection. The caller must call Release when done.
+// It returns an APIError when the token is invalid, the transaction is closed, e.g. because it was idle
+// too long, or the transaction is used by another request.
+func Acquire(token string, kind Kind, connection string, clientID string) (*Transaction, error) {
+	id, ok := parseToken(token, kind, connection, clientID)
+	if !ok {
+		return nil, errors.NewAPIError(http.StatusBadRequest, fmt.Sprintf("Invalid %s token", kind), nil)
+	}
+
+	transactionsMutex.Lock()
+	t, ok := transactions[id]
+	transactionsMutex.Unlock()
+
+	if !ok {
+		details := fmt.Sprintf("The %s is closed or expired", kind)
+		return nil, errors.NewAPIError(http.StatusNotFound, fmt.Sprintf("%s not found", capitalize(string(kind))), &details)
+	}
+
+	if !t.busy.TryLock() {
+		return nil, errors.NewAPIError(http.StatusConflict, fmt.Sprintf("%s is in use by another request", capitalize(string(kind))), nil)
+	}
+
+	if t.closed {
+		t.busy.Unlock()
+		details := fmt.Sprintf("The %s is closed or expired", kind)
+		return nil, errors.NewAPIError(http.StatusNotFound, fmt.Sprintf("%s not found", capitalize(string(kind))), &details)
+	}
+
+	return t, nil
+}
+
+// Release releases the transaction acquired by Begin or Acquire, so it can be used by the next request.
+// The idle timeout starts when the transaction is released.
+func (t *Transaction) Release() {
+	t.lastUsed = time.Now()
+	t.busy.Unlock()
+}
+
+// Close commits or rolls back the transaction and returns the connection to the pool.
+// The transaction must be acquired by the caller, Release must still be called.
+func (t *Transaction) Close(ctx context.Context, commit bool) error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+
+	transactionsMutex.Lock()
+	delete(transactions, t.ID)
+	transactionsMutex.Unlock()
+
+	// A failed commit or rollback leaves the connection in a broken state, the pool
+	// destroys released connections which are not idle
+	defer t.conn.Release()
+
+	if commit {
+		return t.Tx.Commit(ctx)
+	}
+	return t.Tx.Rollback(ctx)
+}
+
+// Token returns the signed token referencing the transaction, it is only valid for the user and connection of the transaction.
+func (t *Transaction) Token() string {
+	return fmt.Sprintf("%s.%s", t.ID, signToken(t.ID, t.Kind, t.Connection, t.ClientID))
+}
+
+// CloseAll rolls back all open transactions, e.g. when the server shuts down.
+func CloseAll() {
+	transactionsMutex.Lock()
+	open := make([]*Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		open = append(open, t)
+	}
+	transactionsMutex.Unlock()
+
+	for _, t := range open {
+		t.busy.Lock()
+		t.Close(context.Background(), false)
+		t.busy.Unlock()
+	}
+}
+
+// countOpen returns the number of open transactions of the kind of the user.
+func countOpen(kind Kind, clientID string) int {
+	transactionsMutex.Lock()
+	defer transactionsMutex.Unlock()
+
+	return countOpenLocked(kind, clientID)
+}
+
+// countOpenLocked returns the number of open transactions of the kind of the user, the transactions mutex must be held.
+func countOpenLocked(kind Kind, clientID string) int {
+	count := 0
+	for _, t := range transactions {
+		if t.Kind == kind && t.ClientID == clientID {
+			count++
+		}
+	}
+	return count
+}
+
+func limitError(kind Kind, limits Limits) error {
+	details := fmt.Sprintf("A maximum of %d open %ss per user is allowed", limits.MaxPerUser, kind)
+	return errors.NewAPIError(http.StatusTooManyRequests, fmt.Sprintf("Too many open %ss", kind), &details)
+}
+
+// signToken returns the HMAC of the transaction id bound to the kind, connection and user.
+func signToken(id string, kind Kind, connection string, clientID string) string {
+	h := hmac.New(sha256.New, tokenSecret)
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", id, kind, connection, clientID)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// parseToken returns the transaction id of the token when its signature is valid for the kind, connection and user.
+func parseToken(token string, kind Kind, connection string, clientID string) (string, bool) {
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+
+	expected := signToken(id, kind, connection, clientID)
+	return id, hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// newID returns a random 128 bit transaction id.
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
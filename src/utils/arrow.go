@@ -1,42 +1,132 @@
 package utils
 
 import (
-	"fmt"
-
 	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	maxDecimal128Precision = 38
+	maxDecimal256Precision = 76
 )
 
+// pgArrayElementOIDs maps the PostgreSQL array type OIDs to the OID of their element type.
+var pgArrayElementOIDs = map[uint32]uint32{
+	pgtype.BoolArrayOID:        pgtype.BoolOID,
+	pgtype.ByteaArrayOID:       pgtype.ByteaOID,
+	pgtype.QCharArrayOID:       pgtype.QCharOID,
+	pgtype.NameArrayOID:        pgtype.NameOID,
+	pgtype.Int2ArrayOID:        pgtype.Int2OID,
+	pgtype.Int4ArrayOID:        pgtype.Int4OID,
+	pgtype.Int8ArrayOID:        pgtype.Int8OID,
+	pgtype.OIDArrayOID:         pgtype.OIDOID,
+	pgtype.TextArrayOID:        pgtype.TextOID,
+	pgtype.BPCharArrayOID:      pgtype.BPCharOID,
+	pgtype.VarcharArrayOID:     pgtype.VarcharOID,
+	pgtype.Float4ArrayOID:      pgtype.Float4OID,
+	pgtype.Float8ArrayOID:      pgtype.Float8OID,
+	pgtype.NumericArrayOID:     pgtype.NumericOID,
+	pgtype.DateArrayOID:        pgtype.DateOID,
+	pgtype.TimeArrayOID:        pgtype.TimeOID,
+	pgtype.TimestampArrayOID:   pgtype.TimestampOID,
+	pgtype.TimestamptzArrayOID: pgtype.TimestamptzOID,
+	pgtype.IntervalArrayOID:    pgtype.IntervalOID,
+	pgtype.UUIDArrayOID:        pgtype.UUIDOID,
+	pgtype.JSONArrayOID:        pgtype.JSONOID,
+	pgtype.JSONBArrayOID:       pgtype.JSONBOID,
+	pgtype.InetArrayOID:        pgtype.InetOID,
+	pgtype.CIDRArrayOID:        pgtype.CIDROID,
+	pgtype.MacaddrArrayOID:     pgtype.MacaddrOID,
+}
+
+// PGArrayElementOID returns the OID of the element type of a PostgreSQL array type.
+// It returns false when the OID is not a known array type.
+func PGArrayElementOID(pgTypeOID uint32) (uint32, bool) {
+	elementOID, ok := pgArrayElementOIDs[pgTypeOID]
+	return elementOID, ok
+}
+
 // PGTypeToArrowType maps PostgreSQL type OIDs to Arrow data types.
-// It returns the corresponding Arrow data type for a given PostgreSQL type OID.
-// If the PostgreSQL type OID is not supported, it returns an error.
-func PGTypeToArrowType(pgTypeOID uint32) (arrow.DataType, error) {
+// The type modifier of the column is used for the precision and scale of numeric columns,
+// for array columns it is the type modifier of the elements.
+// Arrays are mapped to a list of the element type, types without an Arrow equivalent,
+// e.g. inet, enums and composite types, are mapped to their text representation.
+func PGTypeToArrowType(pgTypeOID uint32, typeModifier int32) arrow.DataType {
+	if elementOID, ok := pgArrayElementOIDs[pgTypeOID]; ok {
+		return arrow.ListOf(PGTypeToArrowType(elementOID, typeModifier))
+	}
+
 	switch pgTypeOID {
-	case 20: // BIGINT
-		return arrow.PrimitiveTypes.Int64, nil
-	case 21: // SMALLINT
-		return arrow.PrimitiveTypes.Int16, nil
-	case 23: // INTEGER
-		return arrow.PrimitiveTypes.Int32, nil
-	case 700: // REAL
-		return arrow.PrimitiveTypes.Float32, nil
-	case 701: // DOUBLE PRECISION
-		return arrow.PrimitiveTypes.Float64, nil
-	case 1042, 1043: // CHAR, VARCHAR
-		return arrow.BinaryTypes.String, nil
-	case 1700: // NUMERIC
-		return arrow.PrimitiveTypes.Float64, nil
-	case 16: // BOOLEAN
-		return arrow.FixedWidthTypes.Boolean, nil
-	case 25: // TEXT
-		return arrow.BinaryTypes.String, nil
-	case 1114, 1184: // TIMESTAMP WITHOUT TIME ZONE, TIMESTAMP WITH TIME ZONE
-		return arrow.FixedWidthTypes.Timestamp_ms, nil
-	case 1082: // DATE
-		return arrow.FixedWidthTypes.Date32, nil
-	case 114, 3802: // JSON, JSONB
-		return arrow.BinaryTypes.String, nil
-	// Add more type mappings as needed
+	case pgtype.BoolOID:
+		return arrow.FixedWidthTypes.Boolean
+	case pgtype.Int2OID:
+		return arrow.PrimitiveTypes.Int16
+	case pgtype.Int4OID:
+		return arrow.PrimitiveTypes.Int32
+	case pgtype.Int8OID:
+		return arrow.PrimitiveTypes.Int64
+	case pgtype.OIDOID:
+		return arrow.PrimitiveTypes.Uint32
+	case pgtype.Float4OID:
+		return arrow.PrimitiveTypes.Float32
+	case pgtype.Float8OID:
+		return arrow.PrimitiveTypes.Float64
+	case pgtype.NumericOID:
+		return numericArrowType(typeModifier)
+	case pgtype.ByteaOID:
+		return arrow.BinaryTypes.Binary
+	case pgtype.UUIDOID:
+		return &arrow.FixedSizeBinaryType{ByteWidth: 16}
+	case pgtype.DateOID:
+		return arrow.FixedWidthTypes.Date32
+	case pgtype.TimeOID:
+		return arrow.FixedWidthTypes.Time64us
+	case pgtype.TimestampOID:
+		return &arrow.TimestampType{Unit: arrow.Microsecond}
+	case pgtype.TimestamptzOID:
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	case pgtype.IntervalOID:
+		return arrow.FixedWidthTypes.MonthDayNanoInterval
+	default:
+		// text, varchar, char, name, json(b), inet, enums, composite types, ...
+		return arrow.BinaryTypes.String
+	}
+}
+
+// numericArrowType returns the decimal type for the precision and scale of the numeric type modifier.
+// Unconstrained numerics, numerics exceeding the maximum decimal precision and numerics with a
+// negative scale or a scale larger than the precision are mapped to string to keep their exact value.
+func numericArrowType(typeModifier int32) arrow.DataType {
+	// The type modifier of numeric(p, s) is ((p << 16) | s) + 4, the scale is an 11 bit signed integer
+	if typeModifier < 4 {
+		return arrow.BinaryTypes.String
+	}
+
+	modifier := typeModifier - 4
+	precision := (modifier >> 16) & 0xffff
+	scale := ((modifier & 0x7ff) ^ 1024) - 1024
+
+	switch {
+	case scale < 0 || scale > precision:
+		return arrow.BinaryTypes.String
+	case precision <= maxDecimal128Precision:
+		return &arrow.Decimal128Type{Precision: precision, Scale: scale}
+	case precision <= maxDecimal256Precision:
+		return &arrow.Decimal256Type{Precision: precision, Scale: scale}
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// ToParquetCompatibleType replaces the Arrow types which can not be written to Parquet.
+// Intervals have no lossless Parquet equivalent and are written as text.
+func ToParquetCompatibleType(dataType arrow.DataType) arrow.DataType {
+	switch t := dataType.(type) {
+	case *arrow.MonthDayNanoIntervalType:
+		return arrow.BinaryTypes.String
+	case *arrow.ListType:
+		return arrow.ListOf(ToParquetCompatibleType(t.Elem()))
 	default:
-		return nil, fmt.Errorf("unsupported PostgreSQL type OID: %d", pgTypeOID)
+		return dataType
 	}
 }
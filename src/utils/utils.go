@@ -1,14 +1,21 @@
 package utils
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sogelink-research/pgrest/errors"
+	"github.com/sogelink-research/pgrest/settings"
 )
 
+type contextKey string
+
+const userContextKey contextKey = "user"
+
 // Contains checks if a given string is present in a slice of strings.
 // It returns true if the string is found, otherwise false.
 func Contains(slice []string, str string) bool {
@@ -34,6 +41,93 @@ func GetConnectionNameFromRequest(r *http.Request) (string, error) {
 	return connection, nil
 }
 
+// SetUserInRequest returns a shallow copy of the request with the authenticated user stored in its context.
+func SetUserInRequest(r *http.Request, user settings.UserConfig) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// GetUserFromRequest retrieves the authenticated user from the context of the given HTTP request.
+// It returns nil when the request is not authenticated, e.g. for connections with public auth.
+func GetUserFromRequest(r *http.Request) *settings.UserConfig {
+	user, ok := r.Context().Value(userContextKey).(settings.UserConfig)
+	if !ok {
+		return nil
+	}
+	return &user
+}
+
+// GetQueryNameFromRequest retrieves the name of the named query from the given HTTP request.
+// It expects the query name to be present as a path variable named "name".
+// If the query name is not found or empty, it returns an error of type APIError with a status code of http.StatusBadRequest.
+func GetQueryNameFromRequest(r *http.Request) (string, error) {
+	name := chi.URLParam(r, "name")
+
+	if name == "" {
+		return "", errors.NewAPIError(http.StatusBadRequest, "Query name not found in request", nil)
+	}
+
+	return name, nil
+}
+
+// GetJobIDFromRequest retrieves the job id from the given HTTP request.
+// It expects the job id to be present as a path variable named "id".
+// If the job id is not found or empty, it returns an error of type APIError with a status code of http.StatusBadRequest.
+func GetJobIDFromRequest(r *http.Request) (string, error) {
+	id := chi.URLParam(r, "id")
+
+	if id == "" {
+		return "", errors.NewAPIError(http.StatusBadRequest, "Job id not found in request", nil)
+	}
+
+	return id, nil
+}
+
+// GetCursorFromRequest retrieves the cursor continuation token from the given HTTP request.
+// It expects the token to be present as a path variable named "cursor".
+// If the token is not found or empty, it returns an error of type APIError with a status code of http.StatusBadRequest.
+func GetCursorFromRequest(r *http.Request) (string, error) {
+	cursor := chi.URLParam(r, "cursor")
+
+	if cursor == "" {
+		return "", errors.NewAPIError(http.StatusBadRequest, "Cursor not found in request", nil)
+	}
+
+	return cursor, nil
+}
+
+// GetTransactionFromRequest retrieves the transaction id from the given HTTP request.
+// It expects the transaction id to be present as a path variable named "transaction".
+// If the transaction id is not found or empty, it returns an error of type APIError with a status code of http.StatusBadRequest.
+func GetTransactionFromRequest(r *http.Request) (string, error) {
+	transaction := chi.URLParam(r, "transaction")
+
+	if transaction == "" {
+		return "", errors.NewAPIError(http.StatusBadRequest, "Transaction id not found in request", nil)
+	}
+
+	return transaction, nil
+}
+
+// GetTileFromRequest retrieves the layer name and tile coordinates from the given HTTP request.
+// It expects the path variables "layer", "z", "x" and "y", with z, x and y valid tile coordinates for the zoom level z.
+// If a value is missing or invalid, it returns an error of type APIError with a status code of http.StatusBadRequest.
+func GetTileFromRequest(r *http.Request) (string, int, int, int, error) {
+	layer := chi.URLParam(r, "layer")
+	if layer == "" {
+		return "", 0, 0, 0, errors.NewAPIError(http.StatusBadRequest, "Layer name not found in request", nil)
+	}
+
+	z, errZ := strconv.Atoi(chi.URLParam(r, "z"))
+	x, errX := strconv.Atoi(chi.URLParam(r, "x"))
+	y, errY := strconv.Atoi(chi.URLParam(r, "y"))
+	if errZ != nil || errX != nil || errY != nil || z < 0 || z > 30 || x < 0 || y < 0 || x >= 1<<z || y >= 1<<z {
+		return "", 0, 0, 0, errors.NewAPIError(http.StatusBadRequest, "Invalid tile coordinates", nil)
+	}
+
+	return layer, z, x, y, nil
+}
+
 // getBody reads the body of an HTTP request and returns it as a string.
 func GetBodyString(r *http.Request) string {
 	body, err := io.ReadAll(r.Body)
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// WKB geometry types
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// EWKB flags as used by PostGIS
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+const maxWKBDepth = 64 // Maximum nesting depth of geometry collections
+
+var geoJSONTypes = map[uint32]string{
+	wkbPoint:              "Point",
+	wkbLineString:         "LineString",
+	wkbPolygon:            "Polygon",
+	wkbMultiPoint:         "MultiPoint",
+	wkbMultiLineString:    "MultiLineString",
+	wkbMultiPolygon:       "MultiPolygon",
+	wkbGeometryCollection: "GeometryCollection",
+}
+
+// HexWKBToGeoJSON converts a hex encoded (E)WKB geometry, the text representation of
+// PostGIS geometry and geography values, to a GeoJSON geometry object.
+func HexWKBToGeoJSON(hexWKB string) (map[string]interface{}, error) {
+	data, err := hex.DecodeString(hexWKB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex encoded WKB: %v", err)
+	}
+	return WKBToGeoJSON(data)
+}
+
+// WKBToGeoJSON converts a (E)WKB geometry to a GeoJSON geometry object.
+// Z coordinates are kept, M coordinates are dropped as GeoJSON does not support them.
+func WKBToGeoJSON(data []byte) (map[string]interface{}, error) {
+	reader := &wkbReader{data: data}
+	geometry, err := reader.readGeometry(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WKB: %v", err)
+	}
+	return geometry, nil
+}
+
+// wkbReader reads the (E)WKB geometries from data.
+type wkbReader struct {
+	data      []byte
+	offset    int
+	byteOrder binary.ByteOrder
+}
+
+// readGeometry reads a geometry nested depth levels deep in geometry collections and multi geometries.
+func (r *wkbReader) readGeometry(depth int) (map[string]interface{}, error) {
+	if depth > maxWKBDepth {
+		return nil, fmt.Errorf("geometry nested deeper than %d levels", maxWKBDepth)
+	}
+	if r.offset >= len(r.data) {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+
+	if r.data[r.offset] == 0 {
+		r.byteOrder = binary.BigEndian
+	} else {
+		r.byteOrder = binary.LittleEndian
+	}
+	r.offset++
+
+	geometryType, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	hasZ := geometryType&ewkbZ != 0
+	hasM := geometryType&ewkbM != 0
+	if geometryType&ewkbSRID != 0 {
+		if _, err := r.readUint32(); err != nil {
+			return nil, err
+		}
+	}
+	geometryType &^= ewkbZ | ewkbM | ewkbSRID
+
+	// ISO WKB encodes the dimensions in the type code, e.g. 1001 is a Point Z
+	switch geometryType / 1000 {
+	case 0: // XY or the EWKB flags
+	case 1:
+		hasZ = true
+	case 2:
+		hasM = true
+	case 3:
+		hasZ, hasM = true, true
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %d", geometryType)
+	}
+	geometryType %= 1000
+
+	dimensions := 2
+	if hasZ {
+		dimensions++
+	}
+	if hasM {
+		dimensions++
+	}
+
+	name, ok := geoJSONTypes[geometryType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported geometry type %d", geometryType)
+	}
+
+	if geometryType == wkbGeometryCollection || geometryType == wkbMultiPoint || geometryType == wkbMultiLineString || geometryType == wkbMultiPolygon {
+		// A geometry has at least a byte order and a type
+		count, err := r.readCount(5)
+		if err != nil {
+			return nil, err
+		}
+
+		geometries := make([]interface{}, 0, count)
+		coordinates := make([]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			geometry, err := r.readGeometry(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if geometryType != wkbGeometryCollection && geometry["type"] != geoJSONTypes[geometryType-3] {
+				return nil, fmt.Errorf("%s contains a %s", name, geometry["type"])
+			}
+			geometries = append(geometries, geometry)
+			coordinates = append(coordinates, geometry["coordinates"])
+		}
+
+		if geometryType == wkbGeometryCollection {
+			return map[string]interface{}{"type": name, "geometries": geometries}, nil
+		}
+		return map[string]interface{}{"type": name, "coordinates": coordinates}, nil
+	}
+
+	var coordinates interface{}
+	switch geometryType {
+	case wkbPoint:
+		coordinates, err = r.readPoint(dimensions, hasZ)
+	case wkbLineString:
+		coordinates, err = r.readPoints(dimensions, hasZ)
+	case wkbPolygon:
+		coordinates, err = r.readRings(dimensions, hasZ)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"type": name, "coordinates": coordinates}, nil
+}
+
+// readPoint reads a single point, an empty point (NaN coordinates) is returned as empty coordinates.
+func (r *wkbReader) readPoint(dimensions int, hasZ bool) ([]float64, error) {
+	values := make([]float64, dimensions)
+	for i := range values {
+		v, err := r.readFloat64()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	if math.IsNaN(values[0]) && math.IsNaN(values[1]) {
+		return []float64{}, nil
+	}
+
+	if hasZ {
+		return values[:3], nil
+	}
+	return values[:2], nil
+}
+
+func (r *wkbReader) readPoints(dimensions int, hasZ bool) ([][]float64, error) {
+	count, err := r.readCount(dimensions * 8)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([][]float64, count)
+	for i := range points {
+		if points[i], err = r.readPoint(dimensions, hasZ); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func (r *wkbReader) readRings(dimensions int, hasZ bool) ([][][]float64, error) {
+	// A ring has at least the number of points
+	count, err := r.readCount(4)
+	if err != nil {
+		return nil, err
+	}
+
+	rings := make([][][]float64, count)
+	for i := range rings {
+		if rings[i], err = r.readPoints(dimensions, hasZ); err != nil {
+			return nil, err
+		}
+	}
+	return rings, nil
+}
+
+// readCount reads the number of elements of a geometry of which each element has at least size bytes,
+// it returns an error when the remaining data is too short for the elements, before they are allocated.
+func (r *wkbReader) readCount(size int) (int, error) {
+	count, err := r.readUint32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(count)*uint64(size) > uint64(len(r.data)-r.offset) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	return int(count), nil
+}
+
+func (r *wkbReader) readUint32() (uint32, error) {
+	if r.offset+4 > len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	v := r.byteOrder.Uint32(r.data[r.offset:])
+	r.offset += 4
+	return v, nil
+}
+
+func (r *wkbReader) readFloat64() (float64, error) {
+	if r.offset+8 > len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	v := math.Float64frombits(r.byteOrder.Uint64(r.data[r.offset:]))
+	r.offset += 8
+	return v, nil
+}
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// wkb builds (E)WKB geometries for the tests.
+type wkb struct {
+	buffer bytes.Buffer
+	order  binary.ByteOrder
+}
+
+func newWKB(order binary.ByteOrder) *wkb {
+	return &wkb{order: order}
+}
+
+// geometry writes the byte order and geometry type of a geometry.
+func (w *wkb) geometry(geometryType uint32) *wkb {
+	if w.order == binary.BigEndian {
+		w.buffer.WriteByte(0)
+	} else {
+		w.buffer.WriteByte(1)
+	}
+	return w.uint32(geometryType)
+}
+
+func (w *wkb) uint32(values ...uint32) *wkb {
+	for _, v := range values {
+		binary.Write(&w.buffer, w.order, v)
+	}
+	return w
+}
+
+func (w *wkb) float64(values ...float64) *wkb {
+	for _, v := range values {
+		binary.Write(&w.buffer, w.order, v)
+	}
+	return w
+}
+
+func (w *wkb) bytes() []byte {
+	return w.buffer.Bytes()
+}
+
+func le() *wkb {
+	return newWKB(binary.LittleEndian)
+}
+
+// polygonWKB is a polygon with a hole.
+func polygonWKB() *wkb {
+	return le().geometry(wkbPolygon).uint32(2).
+		uint32(4).float64(0, 0, 10, 0, 10, 10, 0, 0).
+		uint32(4).float64(1, 1, 2, 1, 2, 2, 1, 1)
+}
+
+// collectionWKB is a geometry collection with a point, a multi line string and a nested collection.
+func collectionWKB() *wkb {
+	w := le().geometry(wkbGeometryCollection).uint32(3)
+	w.geometry(wkbPoint).float64(1, 2)
+	w.geometry(wkbMultiLineString).uint32(1).geometry(wkbLineString).uint32(2).float64(0, 0, 1, 1)
+	w.geometry(wkbGeometryCollection).uint32(1).geometry(wkbPoint|ewkbZ).float64(3, 4, 5)
+	return w
+}
+
+func TestWKBToGeoJSON(t *testing.T) {
+	nan := math.NaN()
+
+	tests := []struct {
+		name string
+		data []byte
+		want map[string]interface{}
+	}{
+		{
+			name: "point",
+			data: le().geometry(wkbPoint).float64(1, 2).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2}},
+		},
+		{
+			name: "big endian point",
+			data: newWKB(binary.BigEndian).geometry(wkbPoint).float64(1, 2).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2}},
+		},
+		{
+			name: "point with SRID",
+			data: le().geometry(wkbPoint|ewkbSRID).uint32(4326).float64(1, 2).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2}},
+		},
+		{
+			name: "EWKB point Z",
+			data: le().geometry(wkbPoint|ewkbZ).float64(1, 2, 3).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2, 3}},
+		},
+		{
+			name: "EWKB point M",
+			data: le().geometry(wkbPoint|ewkbM).float64(1, 2, 4).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2}},
+		},
+		{
+			name: "EWKB point ZM with SRID",
+			data: le().geometry(wkbPoint|ewkbZ|ewkbM|ewkbSRID).uint32(28992).float64(1, 2, 3, 4).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2, 3}},
+		},
+		{
+			name: "ISO point Z",
+			data: le().geometry(1000+wkbPoint).float64(1, 2, 3).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2, 3}},
+		},
+		{
+			name: "ISO point M",
+			data: le().geometry(2000+wkbPoint).float64(1, 2, 4).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2}},
+		},
+		{
+			name: "ISO line string ZM",
+			data: le().geometry(3000+wkbLineString).uint32(2).float64(1, 2, 3, 4, 5, 6, 7, 8).bytes(),
+			want: map[string]interface{}{"type": "LineString", "coordinates": [][]float64{{1, 2, 3}, {5, 6, 7}}},
+		},
+		{
+			name: "empty point",
+			data: le().geometry(wkbPoint).float64(nan, nan).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{}},
+		},
+		{
+			name: "empty point Z",
+			data: le().geometry(wkbPoint|ewkbZ).float64(nan, nan, nan).bytes(),
+			want: map[string]interface{}{"type": "Point", "coordinates": []float64{}},
+		},
+		{
+			name: "line string",
+			data: le().geometry(wkbLineString).uint32(2).float64(0, 0, 1, 1).bytes(),
+			want: map[string]interface{}{"type": "LineString", "coordinates": [][]float64{{0, 0}, {1, 1}}},
+		},
+		{
+			name: "empty line string",
+			data: le().geometry(wkbLineString).uint32(0).bytes(),
+			want: map[string]interface{}{"type": "LineString", "coordinates": [][]float64{}},
+		},
+		{
+			name: "polygon with hole",
+			data: polygonWKB().bytes(),
+			want: map[string]interface{}{"type": "Polygon", "coordinates": [][][]float64{
+				{{0, 0}, {10, 0}, {10, 10}, {0, 0}},
+				{{1, 1}, {2, 1}, {2, 2}, {1, 1}},
+			}},
+		},
+		{
+			name: "multi point with empty point",
+			data: le().geometry(wkbMultiPoint).uint32(2).
+				geometry(wkbPoint).float64(1, 2).
+				geometry(wkbPoint).float64(nan, nan).bytes(),
+			want: map[string]interface{}{"type": "MultiPoint", "coordinates": []interface{}{[]float64{1, 2}, []float64{}}},
+		},
+		{
+			name: "multi point Z",
+			data: le().geometry(wkbMultiPoint|ewkbZ|ewkbSRID).uint32(4326, 1).
+				geometry(wkbPoint|ewkbZ).float64(1, 2, 3).bytes(),
+			want: map[string]interface{}{"type": "MultiPoint", "coordinates": []interface{}{[]float64{1, 2, 3}}},
+		},
+		{
+			name: "multi line string",
+			data: le().geometry(wkbMultiLineString).uint32(2).
+				geometry(wkbLineString).uint32(2).float64(0, 0, 1, 1).
+				geometry(wkbLineString).uint32(2).float64(2, 2, 3, 3).bytes(),
+			want: map[string]interface{}{"type": "MultiLineString", "coordinates": []interface{}{
+				[][]float64{{0, 0}, {1, 1}},
+				[][]float64{{2, 2}, {3, 3}},
+			}},
+		},
+		{
+			name: "multi polygon",
+			data: append(le().geometry(wkbMultiPolygon).uint32(1).bytes(), polygonWKB().bytes()...),
+			want: map[string]interface{}{"type": "MultiPolygon", "coordinates": []interface{}{
+				[][][]float64{
+					{{0, 0}, {10, 0}, {10, 10}, {0, 0}},
+					{{1, 1}, {2, 1}, {2, 2}, {1, 1}},
+				},
+			}},
+		},
+		{
+			name: "empty multi polygon",
+			data: le().geometry(wkbMultiPolygon).uint32(0).bytes(),
+			want: map[string]interface{}{"type": "MultiPolygon", "coordinates": []interface{}{}},
+		},
+		{
+			name: "geometry collection",
+			data: collectionWKB().bytes(),
+			want: map[string]interface{}{"type": "GeometryCollection", "geometries": []interface{}{
+				map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2}},
+				map[string]interface{}{"type": "MultiLineString", "coordinates": []interface{}{[][]float64{{0, 0}, {1, 1}}}},
+				map[string]interface{}{"type": "GeometryCollection", "geometries": []interface{}{
+					map[string]interface{}{"type": "Point", "coordinates": []float64{3, 4, 5}},
+				}},
+			}},
+		},
+		{
+			name: "empty geometry collection",
+			data: le().geometry(wkbGeometryCollection).uint32(0).bytes(),
+			want: map[string]interface{}{"type": "GeometryCollection", "geometries": []interface{}{}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := WKBToGeoJSON(test.data)
+			if err != nil {
+				t.Fatalf("WKBToGeoJSON returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("WKBToGeoJSON = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHexWKBToGeoJSON(t *testing.T) {
+	// SELECT 'SRID=4326;POINT(1 2)'::geometry
+	got, err := HexWKBToGeoJSON("0101000020E6100000000000000000F03F0000000000000040")
+	if err != nil {
+		t.Fatalf("HexWKBToGeoJSON returned error: %v", err)
+	}
+	want := map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HexWKBToGeoJSON = %v, want %v", got, want)
+	}
+
+	if _, err := HexWKBToGeoJSON("01010000zz"); err == nil {
+		t.Error("HexWKBToGeoJSON of invalid hex returned no error")
+	}
+}
+
+func TestWKBToGeoJSONInvalid(t *testing.T) {
+	nested := le()
+	for i := 0; i <= maxWKBDepth+1; i++ {
+		nested.geometry(wkbGeometryCollection).uint32(1)
+	}
+	nested.geometry(wkbPoint).float64(1, 2)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "byte order only", data: []byte{1}},
+		{name: "unsupported type", data: le().geometry(8).bytes()},
+		{name: "unsupported ISO dimensions", data: le().geometry(4000+wkbPoint).float64(1, 2, 3, 4, 5).bytes()},
+		{name: "missing SRID", data: le().geometry(wkbPoint | ewkbSRID).bytes()},
+		{name: "truncated point", data: le().geometry(wkbPoint).float64(1).bytes()},
+		{name: "truncated point Z", data: le().geometry(wkbPoint|ewkbZ).float64(1, 2).bytes()},
+		{name: "line string count exceeds data", data: le().geometry(wkbLineString).uint32(3).float64(0, 0, 1, 1).bytes()},
+		{name: "line string huge count", data: le().geometry(wkbLineString).uint32(math.MaxUint32).bytes()},
+		{name: "polygon huge ring count", data: le().geometry(wkbPolygon).uint32(math.MaxUint32).bytes()},
+		{name: "collection huge count", data: le().geometry(wkbGeometryCollection).uint32(math.MaxUint32).bytes()},
+		{name: "multi point with line string", data: le().geometry(wkbMultiPoint).uint32(1).geometry(wkbLineString).uint32(0).bytes()},
+		{name: "multi polygon with collection", data: le().geometry(wkbMultiPolygon).uint32(1).geometry(wkbGeometryCollection).uint32(0).bytes()},
+		{name: "nested too deep", data: nested.bytes()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := WKBToGeoJSON(test.data); err == nil {
+				t.Errorf("WKBToGeoJSON = %v, want error", got)
+			}
+		})
+	}
+}
+
+// TestWKBToGeoJSONTruncated checks every truncation of valid geometries returns an error.
+func TestWKBToGeoJSONTruncated(t *testing.T) {
+	geometries := map[string][]byte{
+		"polygon":             polygonWKB().bytes(),
+		"geometry collection": collectionWKB().bytes(),
+		"point ZM with SRID":  le().geometry(wkbPoint|ewkbZ|ewkbM|ewkbSRID).uint32(4326).float64(1, 2, 3, 4).bytes(),
+	}
+
+	for name, data := range geometries {
+		for i := 0; i < len(data); i++ {
+			if got, err := WKBToGeoJSON(data[:i]); err == nil {
+				t.Errorf("%s truncated to %d bytes: WKBToGeoJSON = %v, want error", name, i, got)
+			}
+		}
+	}
+}